@@ -1,9 +1,12 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -11,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/i18n"
 	"github.com/sunr3d/05-08-2025/internal/interfaces/services"
 	"github.com/sunr3d/05-08-2025/models"
 )
@@ -29,6 +33,36 @@ func New(service services.ArchiveService, logger *zap.Logger, cfg *config.Config
 	}
 }
 
+// POST /v1/archives — с непустым urls собирает архив по ссылкам, без тела или без urls
+// создает пустую задачу.
+func (h *ArchiveAPI) CreateArchiveV1(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "Некорректный запрос: не удалось прочитать тело", nil)
+		return
+	}
+
+	var req createArchiveReq
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			h.logger.Error("ошибка парсинга JSON запроса",
+				zap.String("error", err.Error()),
+				zap.String("method", "CreateArchiveV1"),
+			)
+			writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidJSON, "Некорректный запрос: тело должно быть JSON", nil)
+			return
+		}
+	}
+
+	if len(req.URLs) == 0 {
+		h.CreateEmptyArchive(w, r)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	h.CreateArchive(w, r)
+}
+
 // POST /archive
 func (h *ArchiveAPI) CreateArchive(w http.ResponseWriter, r *http.Request) {
 	var req createArchiveReq
@@ -37,11 +71,11 @@ func (h *ArchiveAPI) CreateArchive(w http.ResponseWriter, r *http.Request) {
 			zap.String("error", err.Error()),
 			zap.String("method", "CreateArchive"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при парсинге JSON запроса", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidJSON, "Некорректный запрос: тело должно быть JSON", nil)
 		return
 	}
-	if len(req.URLs) < 1 || len(req.URLs) > 3 {
-		http.Error(w, "Некорректный запрос: количество URL должно быть от 1 до 3", http.StatusBadRequest)
+	if len(req.URLs) == 0 {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "Некорректный запрос: список URL пуст", nil)
 		return
 	}
 
@@ -52,7 +86,7 @@ func (h *ArchiveAPI) CreateArchive(w http.ResponseWriter, r *http.Request) {
 			zap.String("error", err.Error()),
 			zap.String("method", "CreateArchive"),
 		)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, r, err, nil)
 		return
 	}
 
@@ -60,22 +94,25 @@ func (h *ArchiveAPI) CreateArchive(w http.ResponseWriter, r *http.Request) {
 		ID:        archive.ID,
 		Status:    string(archive.Status),
 		Files:     archive.Files,
-		Errors:    archive.Errors,
+		Errors:    localizeErrors(r, archive.Errors),
+		Attempts:  archive.Attempts,
+		Entries:   entriesResp(r, archive.Entries),
 		CreatedAt: archive.CreatedAt.Format(time.RFC3339),
 	}
 
 	if archive.Status == models.ArchiveStatusReady {
-		resp.ArchiveURL = fmt.Sprintf("/download?archive_id=%s", archive.ID)
+		resp.ArchiveURL = downloadURL(r, archive.ID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		h.logger.Error("ошибка кодирования JSON ответа",
 			zap.String("error", err.Error()),
 			zap.String("archive_id", archive.ID),
 			zap.String("method", "CreateArchive"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при кодировании JSON ответа", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
 	}
 }
 
@@ -88,7 +125,7 @@ func (h *ArchiveAPI) CreateEmptyArchive(w http.ResponseWriter, r *http.Request)
 			zap.String("error", err.Error()),
 			zap.String("method", "CreateEmptyArchive"),
 		)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, r, err, nil)
 		return
 	}
 
@@ -105,15 +142,16 @@ func (h *ArchiveAPI) CreateEmptyArchive(w http.ResponseWriter, r *http.Request)
 			zap.String("archive_id", archive.ID),
 			zap.String("method", "CreateEmptyArchive"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при кодировании JSON ответа", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
 	}
 }
 
 // POST /archive/add-file?archive_id={archive_id}
+// POST /v1/archives/{id}/files
 func (h *ArchiveAPI) AddFile(w http.ResponseWriter, r *http.Request) {
-	archiveID := r.URL.Query().Get("archive_id")
+	archiveID := archiveIDParam(r)
 	if archiveID == "" {
-		http.Error(w, "Некорректный запрос: отсутствует archive_id", http.StatusBadRequest)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
 		return
 	}
 
@@ -124,29 +162,29 @@ func (h *ArchiveAPI) AddFile(w http.ResponseWriter, r *http.Request) {
 			zap.String("archive_id", archiveID),
 			zap.String("method", "AddFile"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при парсинге JSON запроса", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidJSON, "Некорректный запрос: тело должно быть JSON", nil)
 		return
 	}
 
 	if strings.TrimSpace(req.URL) == "" {
-		http.Error(w, "Некорректный запрос: поле URL не может быть пустым", http.StatusBadRequest)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "Некорректный запрос: поле URL не может быть пустым", nil)
 		return
 	}
 
 	ctx := r.Context()
-	resp := addFileResp{}
-	err := h.service.AddFile(ctx, archiveID, req.URL)
-	if err != nil {
+	if err := h.service.AddFile(ctx, archiveID, req.URL); err != nil {
 		h.logger.Error("ошибка при добавлении файла в архив",
 			zap.String("error", err.Error()),
 			zap.String("archive_id", archiveID),
 			zap.String("method", "AddFile"),
 		)
-		resp.Success = false
-		resp.Message = err.Error()
-	} else {
-		resp.Success = true
-		resp.Message = fmt.Sprintf("Файл успешно добавлен к архиву \"%s\"", archiveID)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID, "url": req.URL})
+		return
+	}
+
+	resp := addFileResp{
+		Success: true,
+		Message: i18n.Sprintf(i18n.FromContext(r.Context()), "Файл успешно добавлен к архиву \"%s\"", archiveID),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -156,15 +194,141 @@ func (h *ArchiveAPI) AddFile(w http.ResponseWriter, r *http.Request) {
 			zap.String("archive_id", archiveID),
 			zap.String("method", "AddFile"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при кодировании JSON ответа", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
+	}
+}
+
+// POST /archive/remove-file?archive_id={archive_id}
+// DELETE /v1/archives/{id}/files/{filename}
+func (h *ArchiveAPI) RemoveFile(w http.ResponseWriter, r *http.Request) {
+	archiveID := archiveIDParam(r)
+	if archiveID == "" {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
+		return
+	}
+
+	// в /v1 имя файла — часть пути, в старом роуте — поле тела
+	req := removeFileReq{Filename: r.PathValue("filename")}
+	if req.Filename == "" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Error("ошибка парсинга JSON запроса",
+				zap.String("error", err.Error()),
+				zap.String("archive_id", archiveID),
+				zap.String("method", "RemoveFile"),
+			)
+			writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidJSON, "Некорректный запрос: тело должно быть JSON", nil)
+			return
+		}
+	}
+
+	if strings.TrimSpace(req.Filename) == "" {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidRequest, "Некорректный запрос: поле filename не может быть пустым", nil)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.RemoveFile(ctx, archiveID, req.Filename); err != nil {
+		h.logger.Error("ошибка при удалении файла из архива",
+			zap.String("error", err.Error()),
+			zap.String("archive_id", archiveID),
+			zap.String("method", "RemoveFile"),
+		)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID, "filename": req.Filename})
+		return
+	}
+
+	resp := removeFileResp{
+		Success: true,
+		Message: i18n.Sprintf(i18n.FromContext(r.Context()), "Файл \"%s\" удален из архива \"%s\"", req.Filename, archiveID),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("ошибка кодирования JSON ответа",
+			zap.String("error", err.Error()),
+			zap.String("archive_id", archiveID),
+			zap.String("method", "RemoveFile"),
+		)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
 	}
 }
 
+// POST /archive/finalize?archive_id={archive_id}
+// POST /v1/archives/{id}/finalize
+func (h *ArchiveAPI) FinalizeArchive(w http.ResponseWriter, r *http.Request) {
+	archiveID := archiveIDParam(r)
+	if archiveID == "" {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
+		return
+	}
+
+	ctx := r.Context()
+	archive, err := h.service.Finalize(ctx, archiveID)
+	if err != nil {
+		h.logger.Error("ошибка при завершении архива",
+			zap.String("error", err.Error()),
+			zap.String("archive_id", archiveID),
+			zap.String("method", "FinalizeArchive"),
+		)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID})
+		return
+	}
+
+	resp := getArchiveStatusResp{
+		ID:        archive.ID,
+		Status:    string(archive.Status),
+		Files:     archive.Files,
+		Errors:    localizeErrors(r, archive.Errors),
+		Attempts:  archive.Attempts,
+		Entries:   entriesResp(r, archive.Entries),
+		CreatedAt: archive.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: archive.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if archive.Status == models.ArchiveStatusReady {
+		resp.ArchiveURL = downloadURL(r, archive.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("ошибка кодирования JSON ответа",
+			zap.String("error", err.Error()),
+			zap.String("archive_id", archiveID),
+			zap.String("method", "FinalizeArchive"),
+		)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
+	}
+}
+
+// DELETE /archive?archive_id={archive_id}
+// DELETE /v1/archives/{id}
+func (h *ArchiveAPI) DeleteArchive(w http.ResponseWriter, r *http.Request) {
+	archiveID := archiveIDParam(r)
+	if archiveID == "" {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.DeleteArchive(ctx, archiveID); err != nil {
+		h.logger.Error("ошибка при удалении архива",
+			zap.String("error", err.Error()),
+			zap.String("archive_id", archiveID),
+			zap.String("method", "DeleteArchive"),
+		)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GET /archive/status?archive_id={archive_id}
+// GET /v1/archives/{id}
 func (h *ArchiveAPI) GetArchiveStatus(w http.ResponseWriter, r *http.Request) {
-	archiveID := r.URL.Query().Get("archive_id")
+	archiveID := archiveIDParam(r)
 	if archiveID == "" {
-		http.Error(w, "Некорректный запрос: отсутствует archive_id", http.StatusBadRequest)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
 		return
 	}
 
@@ -176,7 +340,7 @@ func (h *ArchiveAPI) GetArchiveStatus(w http.ResponseWriter, r *http.Request) {
 			zap.String("archive_id", archiveID),
 			zap.String("method", "GetArchiveStatus"),
 		)
-		http.Error(w, "Архив не найден", http.StatusNotFound)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID})
 		return
 	}
 
@@ -184,13 +348,15 @@ func (h *ArchiveAPI) GetArchiveStatus(w http.ResponseWriter, r *http.Request) {
 		ID:        archive.ID,
 		Status:    string(archive.Status),
 		Files:     archive.Files,
-		Errors:    archive.Errors,
+		Errors:    localizeErrors(r, archive.Errors),
+		Attempts:  archive.Attempts,
+		Entries:   entriesResp(r, archive.Entries),
 		CreatedAt: archive.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: archive.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if archive.Status == models.ArchiveStatusReady {
-		resp.ArchiveURL = fmt.Sprintf("/download?archive_id=%s", archive.ID)
+		resp.ArchiveURL = downloadURL(r, archive.ID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -200,15 +366,16 @@ func (h *ArchiveAPI) GetArchiveStatus(w http.ResponseWriter, r *http.Request) {
 			zap.String("archive_id", archiveID),
 			zap.String("method", "GetArchiveStatus"),
 		)
-		http.Error(w, "Внутренняя ошибка сервера при кодировании JSON ответа", http.StatusInternalServerError)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
 	}
 }
 
 // GET /download?archive_id={archive_id}
+// GET /v1/archives/{id}/download
 func (h *ArchiveAPI) DownloadArchive(w http.ResponseWriter, r *http.Request) {
-	archiveID := r.URL.Query().Get("archive_id")
+	archiveID := archiveIDParam(r)
 	if archiveID == "" {
-		http.Error(w, "Некорректный запрос: отсутствует archive_id", http.StatusBadRequest)
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeArchiveIDRequired, "Некорректный запрос: отсутствует archive_id", nil)
 		return
 	}
 
@@ -220,12 +387,12 @@ func (h *ArchiveAPI) DownloadArchive(w http.ResponseWriter, r *http.Request) {
 			zap.String("archive_id", archiveID),
 			zap.String("method", "DownloadArchive"),
 		)
-		http.Error(w, "Архив не найден", http.StatusNotFound)
+		h.writeError(w, r, err, map[string]any{"archive_id": archiveID})
 		return
 	}
 
 	if archive.Status != models.ArchiveStatusReady {
-		http.Error(w, "Архив недоступен для скачивания", http.StatusBadRequest)
+		writeErrorResp(w, r, http.StatusConflict, models.CodeArchiveNotReady, "Архив недоступен для скачивания", map[string]any{"archive_id": archiveID, "status": archive.Status})
 		return
 	}
 
@@ -236,3 +403,52 @@ func (h *ArchiveAPI) DownloadArchive(w http.ResponseWriter, r *http.Request) {
 
 	http.ServeFile(w, r, filePath)
 }
+
+// entriesResp переводит записи о файлах в ответ; время — в RFC3339 с долями секунды.
+func entriesResp(r *http.Request, entries []models.FileEntry) []fileEntryResp {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	lang := i18n.FromContext(r.Context())
+	resp := make([]fileEntryResp, len(entries))
+	for i, e := range entries {
+		resp[i] = fileEntryResp{
+			URL:       e.URL,
+			Name:      e.Name,
+			Size:      e.Size,
+			MIME:      e.MIME,
+			SHA256:    e.SHA256,
+			Status:    string(e.Status),
+			ErrorCode: e.ErrorCode,
+			Error:     i18n.Translate(lang, e.Error),
+			Attempts:  e.Attempts,
+		}
+		if !e.StartedAt.IsZero() {
+			resp[i].StartedAt = e.StartedAt.Format(time.RFC3339Nano)
+		}
+		if !e.FinishedAt.IsZero() {
+			resp[i].FinishedAt = e.FinishedAt.Format(time.RFC3339Nano)
+			if !e.StartedAt.IsZero() {
+				resp[i].DurationMS = e.FinishedAt.Sub(e.StartedAt).Milliseconds()
+			}
+		}
+	}
+	return resp
+}
+
+// archiveIDParam берет ID архива из пути (/v1/archives/{id}), а для старых роутов — из archive_id.
+func archiveIDParam(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("archive_id")
+}
+
+// downloadURL возвращает ссылку на скачивание в той же версии API, что и запрос.
+func downloadURL(r *http.Request, archiveID string) string {
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		return "/v1/archives/" + url.PathEscape(archiveID) + "/download"
+	}
+	return fmt.Sprintf("/download?archive_id=%s", archiveID)
+}
@@ -2,23 +2,58 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/i18n"
+	"github.com/sunr3d/05-08-2025/internal/infra/boltdb"
 	"github.com/sunr3d/05-08-2025/internal/infra/inmem"
+	"github.com/sunr3d/05-08-2025/internal/infra/redisdb"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/middleware"
 	"github.com/sunr3d/05-08-2025/internal/services/archive_service"
+	"github.com/sunr3d/05-08-2025/mocks"
+	"github.com/sunr3d/05-08-2025/models"
 )
 
+var (
+	testPDFURL  string
+	notFoundURL string
+)
+
+func TestMain(m *testing.M) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dummy.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("%PDF-1.4\n%%EOF\n"))
+	})
+	mux.HandleFunc("/status/404", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	ts := httptest.NewServer(mux)
+	testPDFURL = ts.URL + "/dummy.pdf"
+	notFoundURL = ts.URL + "/status/404"
+
+	code := m.Run()
+	ts.Close()
+	os.Exit(code)
+}
+
 func setupTestAPI(t *testing.T) (*ArchiveAPI, func()) {
 	logger := zaptest.NewLogger(t)
 
@@ -37,6 +72,9 @@ func setupTestAPI(t *testing.T) (*ArchiveAPI, func()) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		WorkersCount:         2,
+		WorkerQueueSize:      4,
 	}
 
 	repo := inmem.New(logger, cfg.ArchiveTTL)
@@ -44,19 +82,46 @@ func setupTestAPI(t *testing.T) (*ArchiveAPI, func()) {
 	api := New(service, logger, cfg)
 
 	cleanup := func() {
+		service.Shutdown(context.Background())
 		os.RemoveAll(testDir)
 	}
 
 	return api, cleanup
 }
 
+func waitForArchive(t *testing.T, api *ArchiveAPI, archiveID string) *models.Archive {
+	t.Helper()
+
+	var archive *models.Archive
+	require.Eventually(t, func() bool {
+		a, err := api.service.GetArchive(context.Background(), archiveID)
+		if err != nil {
+			return false
+		}
+		archive = a
+		return a.Status != models.ArchiveStatusBuilding
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return archive
+}
+
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, code string) {
+	t.Helper()
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var resp errorResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, code, resp.Code)
+	assert.NotEmpty(t, resp.Message)
+}
+
 func TestArchiveAPI_CreateArchive_Success(t *testing.T) {
 	api, cleanup := setupTestAPI(t)
 	defer cleanup()
 
 	reqBody := createArchiveReq{
 		URLs: []string{
-			"https://www.w3.org/WAI/ER/tests/xhtml/testfiles/resources/pdf/dummy.pdf",
+			testPDFURL,
 		},
 	}
 
@@ -67,17 +132,21 @@ func TestArchiveAPI_CreateArchive_Success(t *testing.T) {
 
 	api.CreateArchive(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
 
 	var resp createArchiveResp
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	require.NoError(t, err)
 
 	assert.NotEmpty(t, resp.ID)
-	assert.Equal(t, "ready", resp.Status)
-	assert.Len(t, resp.Files, 1)
-	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "building", resp.Status)
+	assert.Empty(t, resp.ArchiveURL)
 	assert.NotEmpty(t, resp.CreatedAt)
+
+	archive := waitForArchive(t, api, resp.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Len(t, archive.Files, 1)
+	assert.Empty(t, archive.Errors)
 }
 
 func TestArchiveAPI_CreateArchive_InvalidJSON(t *testing.T) {
@@ -90,8 +159,8 @@ func TestArchiveAPI_CreateArchive_InvalidJSON(t *testing.T) {
 
 	api.CreateArchive(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Contains(t, w.Body.String(), "Внутренняя ошибка сервера при парсинге JSON запроса")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assertErrorCode(t, w, models.CodeInvalidJSON)
 }
 
 func TestArchiveAPI_CreateArchive_TooManyURLs(t *testing.T) {
@@ -109,8 +178,8 @@ func TestArchiveAPI_CreateArchive_TooManyURLs(t *testing.T) {
 
 	api.CreateArchive(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "количество URL должно быть от 1 до 3")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assertErrorCode(t, w, models.CodeTooManyFiles)
 }
 
 func TestArchiveAPI_CreateArchive_EmptyURLs(t *testing.T) {
@@ -129,7 +198,7 @@ func TestArchiveAPI_CreateArchive_EmptyURLs(t *testing.T) {
 	api.CreateArchive(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "количество URL должно быть от 1 до 3")
+	assertErrorCode(t, w, models.CodeInvalidRequest)
 }
 
 func TestArchiveAPI_CreateEmptyArchive_Success(t *testing.T) {
@@ -164,7 +233,7 @@ func TestArchiveAPI_AddFile_Success(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
 	reqBody := addFileReq{
-		URL: "https://www.w3.org/WAI/ER/tests/xhtml/testfiles/resources/pdf/dummy.pdf",
+		URL: testPDFURL,
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -232,8 +301,168 @@ func TestArchiveAPI_AddFile_InvalidJSON(t *testing.T) {
 
 	api.AddFile(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Contains(t, w.Body.String(), "Внутренняя ошибка сервера при парсинге JSON запроса")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assertErrorCode(t, w, models.CodeInvalidJSON)
+}
+
+func TestArchiveAPI_RemoveFile_Success(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive, err := api.service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, api.service.AddFile(ctx, archive.ID, testPDFURL))
+
+	body, _ := json.Marshal(removeFileReq{Filename: "dummy.pdf"})
+	req := httptest.NewRequest(http.MethodPost, "/archive/remove-file?archive_id="+archive.ID, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	api.RemoveFile(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp removeFileResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+
+	updated, err := api.service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Empty(t, updated.Files)
+}
+
+func TestArchiveAPI_RemoveFile_EmptyFilename(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(removeFileReq{})
+	req := httptest.NewRequest(http.MethodPost, "/archive/remove-file?archive_id=some-id", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	api.RemoveFile(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestArchiveAPI_RemoveFile_ArchiveReady(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive, err := api.service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, api.service.AddFile(ctx, archive.ID, testPDFURL))
+	_, err = api.service.Finalize(ctx, archive.ID)
+	require.NoError(t, err)
+
+	body, _ := json.Marshal(removeFileReq{Filename: "dummy.pdf"})
+	req := httptest.NewRequest(http.MethodPost, "/archive/remove-file?archive_id="+archive.ID, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	api.RemoveFile(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), archive_service.ErrRemoveFromReady.Error())
+}
+
+func TestArchiveAPI_DeleteArchive_Success(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	archive, err := api.service.CreateEmptyArchive(context.Background())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodDelete, "/archive?archive_id="+archive.ID, nil)
+	w := httptest.NewRecorder()
+
+	api.DeleteArchive(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/archive/status?archive_id="+archive.ID, nil)
+	w = httptest.NewRecorder()
+
+	api.GetArchiveStatus(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestArchiveAPI_DeleteArchive_NotFound(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodDelete, "/archive?archive_id=nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	api.DeleteArchive(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestArchiveAPI_FinalizeArchive_Success(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive, err := api.service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, api.service.AddFile(ctx, archive.ID, testPDFURL))
+
+	req := httptest.NewRequest(http.MethodPost, "/archive/finalize?archive_id="+archive.ID, nil)
+	w := httptest.NewRecorder()
+
+	api.FinalizeArchive(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp getArchiveStatusResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, string(models.ArchiveStatusReady), resp.Status)
+	assert.Len(t, resp.Files, 1)
+	assert.Equal(t, "/download?archive_id="+archive.ID, resp.ArchiveURL)
+}
+
+func TestArchiveAPI_FinalizeArchive_MissingArchiveID(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/archive/finalize", nil)
+	w := httptest.NewRecorder()
+
+	api.FinalizeArchive(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestArchiveAPI_FinalizeArchive_NotFound(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/archive/finalize?archive_id=nonexistent", nil)
+	w := httptest.NewRecorder()
+
+	api.FinalizeArchive(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestArchiveAPI_FinalizeArchive_Empty(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	archive, err := api.service.CreateEmptyArchive(context.Background())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/archive/finalize?archive_id="+archive.ID, nil)
+	w := httptest.NewRecorder()
+
+	api.FinalizeArchive(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), archive_service.ErrArchiveEmpty.Error())
 }
 
 func TestArchiveAPI_GetArchiveStatus_Success(t *testing.T) {
@@ -285,6 +514,7 @@ func TestArchiveAPI_GetArchiveStatus_NotFound(t *testing.T) {
 	api.GetArchiveStatus(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
+	assertErrorCode(t, w, models.CodeArchiveNotFound)
 	assert.Contains(t, w.Body.String(), "Архив не найден")
 }
 
@@ -330,7 +560,8 @@ func TestArchiveAPI_DownloadArchive_NotReady(t *testing.T) {
 
 	api.DownloadArchive(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assertErrorCode(t, w, models.CodeArchiveNotReady)
 	assert.Contains(t, w.Body.String(), "Архив недоступен для скачивания")
 }
 
@@ -340,9 +571,9 @@ func TestArchiveAPI_CreateArchive_PartialSuccess(t *testing.T) {
 
 	reqBody := createArchiveReq{
 		URLs: []string{
-			"https://www.w3.org/WAI/ER/tests/xhtml/testfiles/resources/pdf/dummy.pdf",
+			testPDFURL,
 			"invalid-url",
-			"https://httpbin.org/status/404",
+			notFoundURL,
 		},
 	}
 
@@ -353,16 +584,25 @@ func TestArchiveAPI_CreateArchive_PartialSuccess(t *testing.T) {
 
 	api.CreateArchive(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
 
 	var resp createArchiveResp
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	require.NoError(t, err)
+	waitForArchive(t, api, resp.ID)
 
-	assert.Equal(t, "ready", resp.Status)
-	assert.Len(t, resp.Files, 1)
-	assert.Len(t, resp.Errors, 2)
-	assert.NotEmpty(t, resp.ArchiveURL)
+	req = httptest.NewRequest(http.MethodGet, "/archive/status?archive_id="+resp.ID, nil)
+	w = httptest.NewRecorder()
+	api.GetArchiveStatus(w, req)
+
+	var statusResp getArchiveStatusResp
+	err = json.Unmarshal(w.Body.Bytes(), &statusResp)
+	require.NoError(t, err)
+
+	assert.Equal(t, "ready", statusResp.Status)
+	assert.Len(t, statusResp.Files, 1)
+	assert.Len(t, statusResp.Errors, 2)
+	assert.NotEmpty(t, statusResp.ArchiveURL)
 }
 
 func TestArchiveAPI_CreateArchive_ServiceError(t *testing.T) {
@@ -380,7 +620,7 @@ func TestArchiveAPI_CreateArchive_ServiceError(t *testing.T) {
 
 	api.CreateArchive(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusAccepted, w.Code)
 }
 
 func TestArchiveAPI_CreateEmptyArchive_ServiceError(t *testing.T) {
@@ -398,8 +638,10 @@ func TestArchiveAPI_CreateEmptyArchive_ServiceError(t *testing.T) {
 
 	api.CreateEmptyArchive(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assertErrorCode(t, w, models.CodeServerBusy)
 	assert.Contains(t, w.Body.String(), "сервер занят")
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
 }
 
 func TestArchiveAPI_DownloadArchive_Ready(t *testing.T) {
@@ -408,7 +650,7 @@ func TestArchiveAPI_DownloadArchive_Ready(t *testing.T) {
 
 	reqBody := createArchiveReq{
 		URLs: []string{
-			"https://www.w3.org/WAI/ER/tests/xhtml/testfiles/resources/pdf/dummy.pdf",
+			testPDFURL,
 		},
 	}
 
@@ -420,6 +662,7 @@ func TestArchiveAPI_DownloadArchive_Ready(t *testing.T) {
 
 	var resp createArchiveResp
 	json.Unmarshal(w.Body.Bytes(), &resp)
+	waitForArchive(t, api, resp.ID)
 
 	req = httptest.NewRequest(http.MethodGet, "/download?archive_id="+resp.ID, nil)
 	w = httptest.NewRecorder()
@@ -453,14 +696,16 @@ func TestArchiveAPI_AddFile_ServiceError(t *testing.T) {
 
 	api.AddFile(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-	var addResp addFileResp
-	err := json.Unmarshal(w.Body.Bytes(), &addResp)
+	var errResp errorResp
+	err := json.Unmarshal(w.Body.Bytes(), &errResp)
 	require.NoError(t, err)
 
-	assert.False(t, addResp.Success)
-	assert.Contains(t, addResp.Message, "некорректный URL файла")
+	assert.Equal(t, models.CodeInvalidFileURL, errResp.Code)
+	assert.Contains(t, errResp.Message, "некорректный URL файла")
+	assert.Equal(t, resp.ID, errResp.Details["archive_id"])
+	assert.Equal(t, "invalid-url", errResp.Details["url"])
 }
 
 func TestArchiveAPI_GetArchiveStatus_ServiceError(t *testing.T) {
@@ -475,3 +720,430 @@ func TestArchiveAPI_GetArchiveStatus_ServiceError(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.Contains(t, w.Body.String(), "Архив не найден")
 }
+
+func TestArchiveAPI_ListArchives_Paging(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	var created []string
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		api.CreateEmptyArchive(w, httptest.NewRequest(http.MethodPost, "/archive/empty", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp createEmptyArchiveResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		created = append(created, resp.ID)
+	}
+
+	var listed []string
+	target := "/archives?status=empty&sort=created_at&limit=2"
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		w := httptest.NewRecorder()
+		api.ListArchives(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp listArchivesResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		for _, archive := range resp.Archives {
+			assert.Equal(t, "empty", archive.Status)
+			listed = append(listed, archive.ID)
+		}
+
+		if resp.NextCursor == "" {
+			break
+		}
+		target = "/archives?status=empty&sort=created_at&limit=2&cursor=" + resp.NextCursor
+	}
+
+	assert.ElementsMatch(t, created, listed)
+	assert.Len(t, listed, 3)
+
+	w := httptest.NewRecorder()
+	api.ListArchives(w, httptest.NewRequest(http.MethodGet, "/archives?status=ready", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"archives":[]}`, w.Body.String())
+}
+
+func TestArchiveAPI_ListArchives_BadRequest(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"limit не число", "limit=abc"},
+		{"нулевой limit", "limit=0"},
+		{"неизвестный статус", "status=done"},
+		{"неизвестная сортировка", "sort=-name"},
+		{"дата не RFC3339", "created_from=2025-01-08"},
+		{"пустой интервал", "created_from=2025-01-08T10:00:00Z&created_to=2025-01-08T09:00:00Z"},
+		{"битый курсор", "cursor=%21%21%21"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.ListArchives(w, httptest.NewRequest(http.MethodGet, "/archives?"+tt.query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestArchiveAPI_V1_CreateEmptyWithoutBody(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/archives", nil)
+	w := httptest.NewRecorder()
+
+	api.CreateArchiveV1(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp createEmptyArchiveResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "empty", resp.Status)
+}
+
+func TestArchiveAPI_V1_Lifecycle(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(createArchiveReq{URLs: []string{testPDFURL}})
+	req := httptest.NewRequest(http.MethodPost, "/v1/archives", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	api.CreateArchiveV1(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var created createArchiveResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "building", created.Status)
+	waitForArchive(t, api, created.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/archives/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	api.GetArchiveStatus(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status getArchiveStatusResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "ready", status.Status)
+	assert.Equal(t, "/v1/archives/"+created.ID+"/download", status.ArchiveURL)
+
+	req = httptest.NewRequest(http.MethodGet, status.ArchiveURL, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	api.DownloadArchive(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	req = httptest.NewRequest(http.MethodDelete, "/v1/archives/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	api.DeleteArchive(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestArchiveAPI_V1_RemoveFileByPath(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive, err := api.service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, api.service.AddFile(ctx, archive.ID, testPDFURL))
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/archives/"+archive.ID+"/files/dummy.pdf", nil)
+	req.SetPathValue("id", archive.ID)
+	req.SetPathValue("filename", "dummy.pdf")
+	w := httptest.NewRecorder()
+
+	api.RemoveFile(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	updated, err := api.service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Empty(t, updated.Files)
+}
+
+func TestArchiveAPI_ErrorStatus(t *testing.T) {
+	cfg := &config.Config{HTTPTimeout: 30 * time.Second, WorkersCount: 4}
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		retryAfter string
+	}{
+		{"server busy", archive_service.ErrServerBusy, http.StatusTooManyRequests, models.CodeServerBusy, "30"},
+		{"queue full", archive_service.ErrQueueFull, http.StatusServiceUnavailable, models.CodeQueueFull, "8"},
+		{"service stopped", archive_service.ErrServiceStopped, http.StatusServiceUnavailable, models.CodeServiceStopped, "30"},
+		{"too many files", fmt.Errorf("%w: %v", archive_service.ErrMaxFilesPerArchive, 4), http.StatusUnprocessableEntity, models.CodeTooManyFiles, ""},
+		{"invalid url", archive_service.ErrInvalidFileURL, http.StatusUnprocessableEntity, models.CodeInvalidFileURL, ""},
+		{"context done", fmt.Errorf("%w: %v", archive_service.ErrContextDone, context.Canceled), http.StatusServiceUnavailable, models.CodeRequestCanceled, ""},
+		{"internal", assert.AnError, http.StatusInternalServerError, models.CodeInternal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mocks.ArchiveService)
+			svc.On("CreateArchive", mock.Anything, []string{testPDFURL}).Return(nil, tt.err)
+			api := New(svc, zaptest.NewLogger(t), cfg)
+
+			body, _ := json.Marshal(createArchiveReq{URLs: []string{testPDFURL}})
+			req := httptest.NewRequest(http.MethodPost, "/archive", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			api.CreateArchive(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assertErrorCode(t, w, tt.code)
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArchiveAPI_WrongState_Conflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"ready", archive_service.ErrArchiveReady, models.CodeArchiveReady},
+		{"failed", archive_service.ErrArchiveFailed, models.CodeArchiveFailed},
+		{"job", archive_service.ErrArchiveJob, models.CodeArchiveJob},
+		{"full", archive_service.ErrArchiveFull, models.CodeArchiveFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mocks.ArchiveService)
+			svc.On("AddFile", mock.Anything, "test", testPDFURL).Return(tt.err)
+			api := New(svc, zaptest.NewLogger(t), &config.Config{})
+
+			body, _ := json.Marshal(addFileReq{URL: testPDFURL})
+			req := httptest.NewRequest(http.MethodPost, "/archive/add-file?archive_id=test", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			api.AddFile(w, req)
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assertErrorCode(t, w, tt.code)
+			assert.Empty(t, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: %w", archive_service.ErrArchiveGet, infra.ErrArchiveNotFound), http.StatusNotFound, models.CodeArchiveNotFound},
+		{fmt.Errorf("%w: %w", archive_service.ErrArchiveGet, infra.ErrArchiveIDEmpty), http.StatusBadRequest, models.CodeArchiveIDRequired},
+		{fmt.Errorf("%w: %w", archive_service.ErrArchiveGet, fmt.Errorf("%w: %v", infra.ErrContextDone, context.Canceled)), http.StatusServiceUnavailable, models.CodeRequestCanceled},
+		{fmt.Errorf("%w: %w", archive_service.ErrArchiveGet, fmt.Errorf("%w: connection refused", redisdb.ErrRedis)), http.StatusInternalServerError, models.CodeArchiveGetFailed},
+		{fmt.Errorf("%w: %w", archive_service.ErrArchiveGet, fmt.Errorf("%w: unexpected EOF", boltdb.ErrDecode)), http.StatusInternalServerError, models.CodeArchiveGetFailed},
+		{infra.ErrArchiveNotFound, http.StatusNotFound, models.CodeArchiveNotFound},
+		{archive_service.ErrFileNotFound, http.StatusNotFound, models.CodeFileNotFound},
+		{archive_service.ErrArchiveReady, http.StatusConflict, models.CodeArchiveReady},
+		{archive_service.ErrFinalizeFailed, http.StatusConflict, models.CodeArchiveFailed},
+		{archive_service.ErrRemoveFromJob, http.StatusConflict, models.CodeArchiveJob},
+		{archive_service.ErrArchiveFull, http.StatusConflict, models.CodeArchiveFull},
+		{archive_service.ErrArchiveEmpty, http.StatusConflict, models.CodeArchiveEmpty},
+		{archive_service.ErrMaxFilesPerArchive, http.StatusUnprocessableEntity, models.CodeTooManyFiles},
+		{fmt.Errorf("%w: 300 > 100", archive_service.ErrFileTooLarge), http.StatusUnprocessableEntity, models.CodeFileTooLarge},
+		{archive_service.ErrServerBusy, http.StatusTooManyRequests, models.CodeServerBusy},
+		{infra.ErrLimitReached, http.StatusTooManyRequests, models.CodeServerBusy},
+		{archive_service.ErrServiceStopped, http.StatusServiceUnavailable, models.CodeServiceStopped},
+		{infra.ErrInvalidCursor, http.StatusBadRequest, models.CodeInvalidQuery},
+		{fmt.Errorf("%w: %w", archive_service.ErrFileCopyFailed, archive_service.ErrFileTruncated), http.StatusBadGateway, models.CodeDownloadTruncated},
+		{archive_service.ErrMkdirFailed, http.StatusInternalServerError, models.CodeFilesystemError},
+		{assert.AnError, http.StatusInternalServerError, models.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			status, code, message := lookupError(tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.code, code)
+			assert.NotEmpty(t, message)
+		})
+	}
+}
+
+// serviceSentinels — все sentinel-ошибки, которые может вернуть сервис вместе с хранилищем.
+var serviceSentinels = []error{
+	archive_service.ErrContextDone, archive_service.ErrServerBusy, archive_service.ErrServiceStopped,
+	archive_service.ErrQueueFull,
+	archive_service.ErrMaxFilesPerArchive, archive_service.ErrArchiveFull, archive_service.ErrArchiveSave,
+	archive_service.ErrArchiveGet, archive_service.ErrArchiveBuild, archive_service.ErrArchiveDelete,
+	archive_service.ErrArchiveList, archive_service.ErrInvalidListQuery, archive_service.ErrArchiveReady,
+	archive_service.ErrArchiveFailed, archive_service.ErrFinalizeReady, archive_service.ErrFinalizeFailed,
+	archive_service.ErrArchiveEmpty, archive_service.ErrRemoveFromReady, archive_service.ErrRemoveFromFailed,
+	archive_service.ErrFileNotFound, archive_service.ErrUnsupportedFile, archive_service.ErrFileDownloadFailed,
+	archive_service.ErrInvalidFileURL, archive_service.ErrHostNotAllowed, archive_service.ErrFileTruncated,
+	archive_service.ErrFileTooLarge, archive_service.ErrArchiveTooLarge, archive_service.ErrMkdirFailed,
+	archive_service.ErrFileCreateFailed, archive_service.ErrFileOpenFailed, archive_service.ErrFileCopyFailed,
+	archive_service.ErrRemoveFailed, archive_service.ErrArchiveJob, archive_service.ErrFinalizeJob,
+	archive_service.ErrRemoveFromJob,
+	inmem.ErrArchiveNotFound, inmem.ErrArchiveNil, inmem.ErrArchiveIDEmpty, inmem.ErrContextDone,
+	inmem.ErrLimitReached, inmem.ErrInvalidCursor,
+}
+
+func TestErrorMappings_CoverSentinels(t *testing.T) {
+	for _, sentinel := range serviceSentinels {
+		found := false
+		for _, m := range errorMappings {
+			for _, target := range m.errs {
+				if target == sentinel {
+					found = true
+				}
+			}
+		}
+		assert.True(t, found, "нет маппинга для ошибки %q", sentinel)
+	}
+}
+
+func TestCatalog_CoverSentinels(t *testing.T) {
+	for _, sentinel := range serviceSentinels {
+		assert.NotEqual(t, sentinel.Error(), i18n.Translate(i18n.EN, sentinel.Error()), "нет перевода для ошибки %q", sentinel)
+	}
+}
+
+func TestArchiveAPI_Localized(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	localize := func(handler http.HandlerFunc, def i18n.Lang) http.Handler {
+		return middleware.Localize(def)(handler)
+	}
+
+	body, _ := json.Marshal(createArchiveReq{URLs: []string{testPDFURL, "invalid-url", notFoundURL}})
+	req := httptest.NewRequest(http.MethodPost, "/archive", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.5")
+	w := httptest.NewRecorder()
+	localize(api.CreateArchive, i18n.RU).ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var created createArchiveResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	archive := waitForArchive(t, api, created.ID)
+	assert.Contains(t, archive.Errors, "invalid-url - некорректный URL файла")
+
+	t.Run("archive errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/archive/status?archive_id="+created.ID, nil)
+		w := httptest.NewRecorder()
+		localize(api.GetArchiveStatus, i18n.EN).ServeHTTP(w, req)
+
+		var resp getArchiveStatusResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Contains(t, resp.Errors, "invalid-url - invalid file URL")
+		assert.Contains(t, resp.Errors, notFoundURL+" - failed to download the file: HTTP status 404")
+		require.Len(t, resp.Entries, 3)
+		assert.Equal(t, "invalid file URL", resp.Entries[1].Error)
+		assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/archive/status?archive_id=nonexistent", nil)
+		req.Header.Set("Accept-Language", "en")
+		w := httptest.NewRecorder()
+		localize(api.GetArchiveStatus, i18n.RU).ServeHTTP(w, req)
+
+		var resp errorResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, models.CodeArchiveNotFound, resp.Code)
+		assert.Equal(t, "Archive not found", resp.Message)
+	})
+
+	t.Run("add file message", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/archive/empty", nil)
+		w := httptest.NewRecorder()
+		api.CreateEmptyArchive(w, req)
+		var empty createEmptyArchiveResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &empty))
+
+		body, _ := json.Marshal(addFileReq{URL: testPDFURL})
+		req = httptest.NewRequest(http.MethodPost, "/archive/add-file?archive_id="+empty.ID, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "en")
+		w = httptest.NewRecorder()
+		localize(api.AddFile, i18n.RU).ServeHTTP(w, req)
+
+		var resp addFileResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Success)
+		assert.Equal(t, fmt.Sprintf("File successfully added to archive %q", empty.ID), resp.Message)
+	})
+
+	t.Run("default language", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/archive/status?archive_id=nonexistent", nil)
+		req.Header.Set("Accept-Language", "de")
+		w := httptest.NewRecorder()
+		localize(api.GetArchiveStatus, i18n.RU).ServeHTTP(w, req)
+
+		var resp errorResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "Архив не найден", resp.Message)
+	})
+}
+
+func TestArchiveAPI_Entries(t *testing.T) {
+	api, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	urls := []string{testPDFURL, "invalid-url", notFoundURL}
+	body, _ := json.Marshal(createArchiveReq{URLs: urls})
+	req := httptest.NewRequest(http.MethodPost, "/v1/archives", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	api.CreateArchiveV1(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var created createArchiveResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.Len(t, created.Entries, 3)
+	for i, entry := range created.Entries {
+		assert.Equal(t, urls[i], entry.URL)
+		assert.Equal(t, string(models.FileStatusPending), entry.Status)
+	}
+	waitForArchive(t, api, created.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/archives/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	w = httptest.NewRecorder()
+	api.GetArchiveStatus(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp getArchiveStatusResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Entries, 3)
+
+	ok := resp.Entries[0]
+	assert.Equal(t, string(models.FileStatusOK), ok.Status)
+	assert.Equal(t, resp.Files[0], ok.Name)
+	assert.Equal(t, "application/pdf", ok.MIME)
+	assert.Len(t, ok.SHA256, 64)
+	assert.Positive(t, ok.Size)
+	assert.NotEmpty(t, ok.StartedAt)
+	assert.NotEmpty(t, ok.FinishedAt)
+
+	// коды ошибок по файлам совпадают с кодами конверта ошибок
+	assert.Equal(t, models.CodeInvalidFileURL, resp.Entries[1].ErrorCode)
+	assert.Equal(t, models.CodeDownloadFailed, resp.Entries[2].ErrorCode)
+	assert.Equal(t, string(models.FileStatusFailed), resp.Entries[2].Status)
+}
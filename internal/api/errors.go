@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunr3d/05-08-2025/internal/i18n"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/services/archive_service"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+const (
+	minRetryAfter = time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// errorResp — тело любого ответа с ошибкой.
+type errorResp struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// errorMapping связывает sentinel-ошибку со статусом и кодом ответа.
+// Пустой message — в ответ идет текст самой ошибки.
+type errorMapping struct {
+	errs    []error
+	status  int
+	code    string
+	message string
+}
+
+// errorMappings проверяются по порядку, побеждает первое совпадение errors.Is,
+// поэтому более конкретные ошибки стоят выше оберток вроде ErrFileCopyFailed.
+var errorMappings = []errorMapping{
+	{errs: []error{archive_service.ErrContextDone, infra.ErrContextDone}, status: http.StatusServiceUnavailable, code: models.CodeRequestCanceled},
+	{errs: []error{archive_service.ErrServerBusy, infra.ErrLimitReached}, status: http.StatusTooManyRequests, code: models.CodeServerBusy},
+	{errs: []error{archive_service.ErrServiceStopped}, status: http.StatusServiceUnavailable, code: models.CodeServiceStopped},
+	{errs: []error{archive_service.ErrQueueFull}, status: http.StatusServiceUnavailable, code: models.CodeQueueFull},
+	{errs: []error{archive_service.ErrInvalidListQuery, infra.ErrInvalidCursor}, status: http.StatusBadRequest, code: models.CodeInvalidQuery},
+	{errs: []error{infra.ErrArchiveIDEmpty}, status: http.StatusBadRequest, code: models.CodeArchiveIDRequired},
+	{errs: []error{infra.ErrArchiveNotFound}, status: http.StatusNotFound, code: models.CodeArchiveNotFound, message: "Архив не найден"},
+	{errs: []error{archive_service.ErrFileNotFound}, status: http.StatusNotFound, code: models.CodeFileNotFound},
+	{errs: []error{archive_service.ErrArchiveReady, archive_service.ErrFinalizeReady, archive_service.ErrRemoveFromReady}, status: http.StatusConflict, code: models.CodeArchiveReady},
+	{errs: []error{archive_service.ErrArchiveFailed, archive_service.ErrFinalizeFailed, archive_service.ErrRemoveFromFailed}, status: http.StatusConflict, code: models.CodeArchiveFailed},
+	{errs: []error{archive_service.ErrArchiveJob, archive_service.ErrFinalizeJob, archive_service.ErrRemoveFromJob}, status: http.StatusConflict, code: models.CodeArchiveJob},
+	{errs: []error{archive_service.ErrArchiveFull}, status: http.StatusConflict, code: models.CodeArchiveFull},
+	{errs: []error{archive_service.ErrArchiveEmpty}, status: http.StatusConflict, code: models.CodeArchiveEmpty},
+	{errs: []error{archive_service.ErrMaxFilesPerArchive}, status: http.StatusUnprocessableEntity, code: models.CodeTooManyFiles},
+	{errs: []error{archive_service.ErrInvalidFileURL}, status: http.StatusUnprocessableEntity, code: models.CodeInvalidFileURL},
+	{errs: []error{archive_service.ErrHostNotAllowed}, status: http.StatusUnprocessableEntity, code: models.CodeHostNotAllowed},
+	{errs: []error{archive_service.ErrUnsupportedFile}, status: http.StatusUnprocessableEntity, code: models.CodeUnsupportedFile},
+	{errs: []error{archive_service.ErrFileTooLarge}, status: http.StatusUnprocessableEntity, code: models.CodeFileTooLarge},
+	{errs: []error{archive_service.ErrArchiveTooLarge}, status: http.StatusUnprocessableEntity, code: models.CodeArchiveTooLarge},
+	{errs: []error{archive_service.ErrFileTruncated}, status: http.StatusBadGateway, code: models.CodeDownloadTruncated},
+	{errs: []error{archive_service.ErrFileDownloadFailed}, status: http.StatusBadGateway, code: models.CodeDownloadFailed},
+	{errs: []error{archive_service.ErrArchiveBuild}, status: http.StatusInternalServerError, code: models.CodeArchiveBuildFailed},
+	{errs: []error{archive_service.ErrArchiveGet}, status: http.StatusInternalServerError, code: models.CodeArchiveGetFailed},
+	{errs: []error{archive_service.ErrArchiveSave}, status: http.StatusInternalServerError, code: models.CodeArchiveSaveFailed},
+	{errs: []error{archive_service.ErrArchiveDelete}, status: http.StatusInternalServerError, code: models.CodeArchiveDeleteFailed},
+	{errs: []error{archive_service.ErrArchiveList}, status: http.StatusInternalServerError, code: models.CodeArchiveListFailed},
+	{
+		errs: []error{
+			archive_service.ErrMkdirFailed,
+			archive_service.ErrFileCreateFailed,
+			archive_service.ErrFileOpenFailed,
+			archive_service.ErrFileCopyFailed,
+			archive_service.ErrRemoveFailed,
+		},
+		status: http.StatusInternalServerError,
+		code:   models.CodeFilesystemError,
+	},
+	{errs: []error{infra.ErrArchiveNil}, status: http.StatusInternalServerError, code: models.CodeInternal},
+}
+
+// lookupError возвращает статус, код и сообщение для ошибки сервиса или хранилища.
+func lookupError(err error) (int, string, string) {
+	for _, m := range errorMappings {
+		for _, target := range m.errs {
+			if !errors.Is(err, target) {
+				continue
+			}
+			if m.message != "" {
+				return m.status, m.code, m.message
+			}
+			return m.status, m.code, err.Error()
+		}
+	}
+	return http.StatusInternalServerError, models.CodeInternal, err.Error()
+}
+
+// retryAfter оценивает, когда повторить запрос, отклоненный из-за нагрузки.
+// Место в лимите задач освобождается, когда одна из них соберется, а это занимает
+// порядка HTTP_TIMEOUT; место в очереди — когда любой из воркеров возьмет задачу.
+func (h *ArchiveAPI) retryAfter(err error) (time.Duration, bool) {
+	var d time.Duration
+	switch {
+	case errors.Is(err, archive_service.ErrServerBusy), errors.Is(err, infra.ErrLimitReached):
+		d = h.cfg.HTTPTimeout
+	case errors.Is(err, archive_service.ErrQueueFull):
+		d = h.cfg.HTTPTimeout / time.Duration(max(h.cfg.WorkersCount, 1))
+	case errors.Is(err, archive_service.ErrServiceStopped):
+		d = h.cfg.HTTPTimeout
+	default:
+		return 0, false
+	}
+	return min(max(d, minRetryAfter), maxRetryAfter), true
+}
+
+// writeError отвечает конвертом ошибки, статус и код берутся из errorMappings.
+// Для отказов из-за нагрузки добавляется заголовок Retry-After в секундах.
+func (h *ArchiveAPI) writeError(w http.ResponseWriter, r *http.Request, err error, details map[string]any) {
+	if d, ok := h.retryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+	status, code, message := lookupError(err)
+	writeErrorResp(w, r, status, code, message, details)
+}
+
+// writeErrorResp отвечает конвертом ошибки с явными статусом и кодом.
+// message переводится на язык запроса.
+func writeErrorResp(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResp{
+		Code:    code,
+		Message: i18n.Translate(i18n.FromContext(r.Context()), message),
+		Details: details,
+	})
+}
+
+// localizeErrors переводит записи archive.Errors вида "<url> - <ошибка>" на язык запроса.
+func localizeErrors(r *http.Request, errs []string) []string {
+	lang := i18n.FromContext(r.Context())
+	if lang == i18n.Source || len(errs) == 0 {
+		return errs
+	}
+
+	localized := make([]string, len(errs))
+	for i, entry := range errs {
+		if url, message, ok := strings.Cut(entry, " - "); ok {
+			localized[i] = url + " - " + i18n.Translate(lang, message)
+			continue
+		}
+		localized[i] = i18n.Translate(lang, entry)
+	}
+	return localized
+}
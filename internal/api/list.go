@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// GET /archives
+// GET /v1/archives?status={status,...}&created_from={RFC3339}&created_to={RFC3339}&sort={[-]field}&limit={n}&cursor={cursor}
+func (h *ArchiveAPI) ListArchives(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResp(w, r, http.StatusBadRequest, models.CodeInvalidQuery, "Некорректный запрос: "+err.Error(), nil)
+		return
+	}
+
+	ctx := r.Context()
+	page, err := h.service.ListArchives(ctx, query)
+	if err != nil {
+		h.logger.Error("ошибка при получении списка архивов",
+			zap.String("error", err.Error()),
+			zap.String("method", "ListArchives"),
+		)
+		h.writeError(w, r, err, nil)
+		return
+	}
+
+	resp := listArchivesResp{
+		Archives:   make([]getArchiveStatusResp, 0, len(page.Archives)),
+		NextCursor: page.NextCursor,
+	}
+	for _, archive := range page.Archives {
+		item := getArchiveStatusResp{
+			ID:        archive.ID,
+			Status:    string(archive.Status),
+			Files:     archive.Files,
+			Errors:    localizeErrors(r, archive.Errors),
+			Attempts:  archive.Attempts,
+			Entries:   entriesResp(r, archive.Entries),
+			CreatedAt: archive.CreatedAt.Format(time.RFC3339),
+			UpdatedAt: archive.UpdatedAt.Format(time.RFC3339),
+		}
+		if archive.Status == models.ArchiveStatusReady {
+			item.ArchiveURL = downloadURL(r, archive.ID)
+		}
+		resp.Archives = append(resp.Archives, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("ошибка кодирования JSON ответа",
+			zap.String("error", err.Error()),
+			zap.String("method", "ListArchives"),
+		)
+		writeErrorResp(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера при кодировании JSON ответа", nil)
+	}
+}
+
+// parseListQuery разбирает параметры списка. Сортировка по умолчанию — сначала новые.
+// status можно передать несколько раз или через запятую.
+func parseListQuery(values url.Values) (models.ArchiveQuery, error) {
+	query := models.ArchiveQuery{
+		SortBy: models.SortByCreatedAt,
+		Desc:   true,
+		Cursor: values.Get("cursor"),
+	}
+
+	for _, value := range values["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				query.Statuses = append(query.Statuses, models.ArchiveStatus(status))
+			}
+		}
+	}
+
+	var err error
+	if query.CreatedFrom, err = parseTimeParam(values, "created_from"); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = parseTimeParam(values, "created_to"); err != nil {
+		return query, err
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		field, desc := strings.CutPrefix(sort, "-")
+		query.SortBy = models.ArchiveSortField(field)
+		query.Desc = desc
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("limit должен быть положительным числом")
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
+
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s должен быть в формате RFC3339", name)
+	}
+	return t, nil
+}
@@ -6,12 +6,30 @@ type createArchiveReq struct {
 }
 
 type createArchiveResp struct {
-	ID         string   `json:"id"`
-	Status     string   `json:"status"`
-	Files      []string `json:"files"`
-	Errors     []string `json:"errors,omitempty"`
-	CreatedAt  string   `json:"created_at"`
-	ArchiveURL string   `json:"archive_url,omitempty"`
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Files      []string        `json:"files"`
+	Errors     []string        `json:"errors,omitempty"`
+	Attempts   map[string]int  `json:"attempts,omitempty"`
+	Entries    []fileEntryResp `json:"entries,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	ArchiveURL string          `json:"archive_url,omitempty"`
+}
+
+// fileEntryResp — результат по одному URL архива.
+type fileEntryResp struct {
+	URL        string `json:"url"`
+	Name       string `json:"name,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	MIME       string `json:"mime,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
+	Status     string `json:"status"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
 }
 
 // CreateEmptyArchive
@@ -31,13 +49,31 @@ type addFileResp struct {
 	Message string `json:"message,omitempty"`
 }
 
+// RemoveFile
+type removeFileReq struct {
+	Filename string `json:"filename"`
+}
+
+type removeFileResp struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
 // GetArchiveStatus
 type getArchiveStatusResp struct {
-	ID         string   `json:"id"`
-	Status     string   `json:"status"`
-	Files      []string `json:"files"`
-	Errors     []string `json:"errors,omitempty"`
-	CreatedAt  string   `json:"created_at"`
-	UpdatedAt  string   `json:"updated_at"`
-	ArchiveURL string   `json:"archive_url,omitempty"`
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Files      []string        `json:"files"`
+	Errors     []string        `json:"errors,omitempty"`
+	Attempts   map[string]int  `json:"attempts,omitempty"`
+	Entries    []fileEntryResp `json:"entries,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	UpdatedAt  string          `json:"updated_at"`
+	ArchiveURL string          `json:"archive_url,omitempty"`
+}
+
+// ListArchives
+type listArchivesResp struct {
+	Archives   []getArchiveStatusResp `json:"archives"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
 }
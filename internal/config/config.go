@@ -1,15 +1,61 @@
 package config
 
-import "time"
+import (
+	"net/netip"
+	"time"
+
+	"github.com/sunr3d/05-08-2025/internal/hostpolicy"
+)
+
+const (
+	ContentCheckHeader = "header"
+	ContentCheckSniff  = "sniff"
+	ContentCheckBoth   = "both"
+
+	OrphanZipAdopt  = "adopt"
+	OrphanZipDelete = "delete"
+
+	StorageMemory = "memory"
+	StorageBolt   = "bolt"
+	StorageRedis  = "redis"
+)
 
 type Config struct {
-	HTTPPort             string        `envconfig:"HTTP_PORT" default:"8080"`
-	HTTPTimeout          time.Duration `envconfig:"HTTP_TIMEOUT" default:"30s"`
-	LogLevel             string        `envconfig:"LOG_LEVEL" default:"info"`
-	AllowedExtensions    []string      `envconfig:"ALLOWED_EXTENSIONS" default:"application/pdf,image/jpeg,image/jpg"`
-	MaxArchivesInProcess int           `envconfig:"MAX_ARCHIVES_IN_PROCESS" default:"3"`
-	MaxFilesPerArchive   int           `envconfig:"MAX_FILES_PER_ARCHIVE" default:"3"`
-	ArchiveTTL           time.Duration `envconfig:"ARCHIVE_TTL" default:"1h"`
-	ArchivesDir          string        `envconfig:"ARCHIVES_DIR" default:"./data/archives"`
-	TempDir              string        `envconfig:"TEMP_DIR" default:"./data/temp"`
+	HTTPPort               string               `envconfig:"HTTP_PORT" default:"8080"`
+	HTTPTimeout            time.Duration        `envconfig:"HTTP_TIMEOUT" default:"30s"`
+	LogLevel               string               `envconfig:"LOG_LEVEL" default:"info"`
+	DefaultLanguage        string               `envconfig:"DEFAULT_LANGUAGE" default:"ru"`
+	AllowedExtensions      []string             `envconfig:"ALLOWED_EXTENSIONS" default:"application/pdf,image/jpeg,image/jpg"`
+	ContentCheckMode       string               `envconfig:"CONTENT_CHECK_MODE" default:"sniff"`
+	SSRFAllowCIDRs         []netip.Prefix       `envconfig:"SSRF_ALLOW_CIDRS"`
+	SSRFDenyCIDRs          []netip.Prefix       `envconfig:"SSRF_DENY_CIDRS"`
+	AllowedHosts           []hostpolicy.Pattern `envconfig:"ALLOWED_HOSTS"`
+	DeniedHosts            []hostpolicy.Pattern `envconfig:"DENIED_HOSTS"`
+	MaxArchivesInProcess   int                  `envconfig:"MAX_ARCHIVES_IN_PROCESS" default:"3"`
+	MaxFilesPerArchive     int                  `envconfig:"MAX_FILES_PER_ARCHIVE" default:"3"`
+	StorageDriver          string               `envconfig:"STORAGE_DRIVER" default:"memory"`
+	StoragePath            string               `envconfig:"STORAGE_PATH" default:"./data/archives.db"`
+	RedisAddr              string               `envconfig:"REDIS_ADDR" default:"localhost:6379"`
+	RedisPassword          string               `envconfig:"REDIS_PASSWORD"`
+	RedisDB                int                  `envconfig:"REDIS_DB" default:"0"`
+	RedisKeyPrefix         string               `envconfig:"REDIS_KEY_PREFIX" default:"{archiver}:"`
+	ArchiveTTL             time.Duration        `envconfig:"ARCHIVE_TTL" default:"1h"`
+	ArchiveIdleFinalize    time.Duration        `envconfig:"ARCHIVE_IDLE_FINALIZE" default:"0s"`
+	JanitorInterval        time.Duration        `envconfig:"JANITOR_INTERVAL" default:"1m"`
+	RetentionEmpty         time.Duration        `envconfig:"RETENTION_EMPTY" default:"1h"`
+	RetentionBuilding      time.Duration        `envconfig:"RETENTION_BUILDING" default:"1h"`
+	RetentionReady         time.Duration        `envconfig:"RETENTION_READY" default:"24h"`
+	RetentionFailed        time.Duration        `envconfig:"RETENTION_FAILED" default:"1h"`
+	OrphanZipPolicy        string               `envconfig:"ORPHAN_ZIP_POLICY" default:"adopt"`
+	ArchivesDir            string               `envconfig:"ARCHIVES_DIR" default:"./data/archives"`
+	TempDir                string               `envconfig:"TEMP_DIR" default:"./data/temp"`
+	WorkersCount           int                  `envconfig:"WORKERS_COUNT" default:"3"`
+	WorkerQueueSize        int                  `envconfig:"WORKER_QUEUE_SIZE" default:"16"`
+	DownloadsPerArchive    int                  `envconfig:"DOWNLOADS_PER_ARCHIVE" default:"3"`
+	MaxParallelDownloads   int                  `envconfig:"MAX_PARALLEL_DOWNLOADS" default:"10"`
+	DownloadRetryAttempts  int                  `envconfig:"DOWNLOAD_RETRY_ATTEMPTS" default:"3"`
+	DownloadRetryBaseDelay time.Duration        `envconfig:"DOWNLOAD_RETRY_BASE_DELAY" default:"500ms"`
+	DownloadRetryMaxDelay  time.Duration        `envconfig:"DOWNLOAD_RETRY_MAX_DELAY" default:"10s"`
+	MaxFileSize            int64                `envconfig:"MAX_FILE_SIZE" default:"52428800"`
+	MaxArchiveSize         int64                `envconfig:"MAX_ARCHIVE_SIZE" default:"157286400"`
 }
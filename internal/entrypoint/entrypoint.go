@@ -1,15 +1,24 @@
 package entrypoint
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"github.com/sunr3d/05-08-2025/internal/api"
 	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/i18n"
+	"github.com/sunr3d/05-08-2025/internal/infra/boltdb"
 	"github.com/sunr3d/05-08-2025/internal/infra/inmem"
+	"github.com/sunr3d/05-08-2025/internal/infra/redisdb"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/janitor"
 	"github.com/sunr3d/05-08-2025/internal/middleware"
 	"github.com/sunr3d/05-08-2025/internal/server"
 	"github.com/sunr3d/05-08-2025/internal/services/archive_service"
@@ -28,22 +37,110 @@ func Run(cfg *config.Config, log *zap.Logger) error {
 		log.Info("директория для архивов создана", zap.String("path", cfg.ArchivesDir))
 	}
 
-	db := inmem.New(log, cfg.ArchiveTTL)
+	lang, ok := i18n.Parse(cfg.DefaultLanguage)
+	if !ok {
+		return fmt.Errorf("неизвестный DEFAULT_LANGUAGE: %q", cfg.DefaultLanguage)
+	}
+
+	switch cfg.ContentCheckMode {
+	case config.ContentCheckHeader, config.ContentCheckSniff, config.ContentCheckBoth:
+	default:
+		return fmt.Errorf("неизвестный CONTENT_CHECK_MODE: %q", cfg.ContentCheckMode)
+	}
+
+	// опечатка в политике не должна превращаться в удаление всех zip при старте
+	switch cfg.OrphanZipPolicy {
+	case config.OrphanZipAdopt, config.OrphanZipDelete:
+	default:
+		return fmt.Errorf("неизвестный ORPHAN_ZIP_POLICY: %q", cfg.OrphanZipPolicy)
+	}
+
+	db, err := newDatabase(cfg, log)
+	if err != nil {
+		return err
+	}
 	svc := archive_service.New(log, cfg, db)
 	controller := api.New(svc, log, cfg)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("POST /archive", controller.CreateArchive)
-	mux.HandleFunc("POST /archive/empty", controller.CreateEmptyArchive)
-	mux.HandleFunc("POST /archive/add-file", controller.AddFile)
-	mux.HandleFunc("GET /archive/status", controller.GetArchiveStatus)
-	mux.HandleFunc("GET /download", controller.DownloadArchive)
-
-	router := http.Handler(mux)
+	router := http.Handler(newRouter(controller))
 	router = middleware.JSONValidator()(router)
 	router = middleware.ReqLogger(log)(router)
 	router = middleware.Recovery(log)(router)
+	router = middleware.Localize(lang)(router)
 
 	srv := server.New(cfg.HTTPPort, router, log)
+	cleaner := janitor.New(log, cfg, db, svc)
+	if _, err := cleaner.Reconcile(context.Background()); err != nil {
+		return fmt.Errorf("ошибка сверки файлов при старте: %w", err)
+	}
+	cleaner.Start()
+
+	srv.OnShutdown(cleaner.Shutdown)
+	srv.OnShutdown(svc.Shutdown)
+	if closer, ok := db.(io.Closer); ok {
+		srv.OnShutdown(func(context.Context) error { return closer.Close() })
+	}
 	return srv.Start()
 }
+
+// legacyDeprecatedAt — дата, с которой старые роуты считаются устаревшими (уходит в
+// заголовок Deprecation).
+var legacyDeprecatedAt = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// newRouter регистрирует роуты API. Middleware поверх него навешивает Run.
+func newRouter(controller *api.ArchiveAPI) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/archives", controller.CreateArchiveV1)
+	mux.HandleFunc("GET /v1/archives", controller.ListArchives)
+	mux.HandleFunc("GET /v1/archives/{id}", controller.GetArchiveStatus)
+	mux.HandleFunc("DELETE /v1/archives/{id}", controller.DeleteArchive)
+	mux.HandleFunc("POST /v1/archives/{id}/files", controller.AddFile)
+	mux.HandleFunc("DELETE /v1/archives/{id}/files/{filename}", controller.RemoveFile)
+	mux.HandleFunc("POST /v1/archives/{id}/finalize", controller.FinalizeArchive)
+	mux.HandleFunc("GET /v1/archives/{id}/download", controller.DownloadArchive)
+
+	// старые роуты остаются для совместимости и отдают заголовок Deprecation
+	legacy := func(pattern string, handler http.HandlerFunc, successor string) {
+		mux.Handle(pattern, middleware.Deprecated(legacyDeprecatedAt, successor)(handler))
+	}
+	legacy("POST /archive", controller.CreateArchive, "/v1/archives")
+	legacy("POST /archive/empty", controller.CreateEmptyArchive, "/v1/archives")
+	legacy("POST /archive/add-file", controller.AddFile, "/v1/archives/{id}/files")
+	legacy("POST /archive/remove-file", controller.RemoveFile, "/v1/archives/{id}/files/{filename}")
+	legacy("POST /archive/finalize", controller.FinalizeArchive, "/v1/archives/{id}/finalize")
+	legacy("GET /archive/status", controller.GetArchiveStatus, "/v1/archives/{id}")
+	legacy("GET /archives", controller.ListArchives, "/v1/archives")
+	legacy("DELETE /archive", controller.DeleteArchive, "/v1/archives/{id}")
+	legacy("GET /download", controller.DownloadArchive, "/v1/archives/{id}/download")
+
+	return mux
+}
+
+func newDatabase(cfg *config.Config, log *zap.Logger) (infra.Database, error) {
+	switch cfg.StorageDriver {
+	case config.StorageMemory, "":
+		log.Info("хранилище: in-memory")
+		return inmem.New(log, cfg.ArchiveTTL), nil
+	case config.StorageBolt:
+		db, err := boltdb.New(log, cfg.StoragePath, cfg.ArchiveTTL)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть хранилище: %w", err)
+		}
+		log.Info("хранилище: bbolt", zap.String("path", cfg.StoragePath))
+		return db, nil
+	case config.StorageRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("не удалось подключиться к Redis: %w", err)
+		}
+		log.Info("хранилище: redis", zap.String("addr", cfg.RedisAddr))
+		return redisdb.New(log, client, cfg.RedisKeyPrefix, cfg.ArchiveTTL), nil
+	default:
+		return nil, fmt.Errorf("неизвестный STORAGE_DRIVER: %q", cfg.StorageDriver)
+	}
+}
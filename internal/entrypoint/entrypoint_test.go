@@ -0,0 +1,112 @@
+package entrypoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/sunr3d/05-08-2025/internal/api"
+	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/mocks"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+func TestNewRouter_Dispatch(t *testing.T) {
+	archive := &models.Archive{ID: "abc", Status: models.ArchiveStatusBuilding, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		setup      func(s *mocks.ArchiveService)
+		wantStatus int
+		wantLink   string
+	}{
+		{
+			name:   "v1 статус архива",
+			method: http.MethodGet, target: "/v1/archives/abc",
+			setup: func(s *mocks.ArchiveService) {
+				s.On("GetArchive", mock.Anything, "abc").Return(archive, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "v1 удаление архива",
+			method: http.MethodDelete, target: "/v1/archives/abc",
+			setup: func(s *mocks.ArchiveService) {
+				s.On("DeleteArchive", mock.Anything, "abc").Return(nil)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "v1 удаление файла с экранированным именем",
+			method: http.MethodDelete, target: "/v1/archives/abc/files/my%20file.pdf",
+			setup: func(s *mocks.ArchiveService) {
+				s.On("RemoveFile", mock.Anything, "abc", "my file.pdf").Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "старый статус архива",
+			method: http.MethodGet, target: "/archive/status?archive_id=abc",
+			setup: func(s *mocks.ArchiveService) {
+				s.On("GetArchive", mock.Anything, "abc").Return(archive, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantLink:   `</v1/archives/abc>; rel="successor-version"`,
+		},
+		{
+			name:   "старое удаление файла",
+			method: http.MethodPost, target: "/archive/remove-file?archive_id=abc",
+			body: `{"filename":"a.pdf"}`,
+			setup: func(s *mocks.ArchiveService) {
+				s.On("RemoveFile", mock.Anything, "abc", "a.pdf").Return(nil)
+			},
+			wantStatus: http.StatusOK,
+			wantLink:   `</v1/archives/abc/files/{filename}>; rel="successor-version"`,
+		},
+		{
+			name:   "старое удаление архива",
+			method: http.MethodDelete, target: "/archive?archive_id=abc",
+			setup: func(s *mocks.ArchiveService) {
+				s.On("DeleteArchive", mock.Anything, "abc").Return(nil)
+			},
+			wantStatus: http.StatusNoContent,
+			wantLink:   `</v1/archives/abc>; rel="successor-version"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(mocks.ArchiveService)
+			tt.setup(service)
+			router := newRouter(api.New(service, zaptest.NewLogger(t), &config.Config{}))
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantLink == "" {
+				assert.Empty(t, rec.Header().Get("Deprecation"))
+				assert.Empty(t, rec.Header().Get("Link"))
+			} else {
+				assert.Equal(t, deprecation, rec.Header().Get("Deprecation"))
+				assert.Equal(t, tt.wantLink, rec.Header().Get("Link"))
+			}
+			service.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package hostpolicy
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrHostRejected   = errors.New("хост запрещен политикой")
+	ErrInvalidPattern = errors.New("некорректный шаблон хоста")
+)
+
+// RejectedError описывает, почему хост не прошел проверку.
+type RejectedError struct {
+	Host string
+	// Rule — сработавший deny шаблон; пусто, если хост не попал в allow список.
+	Rule string
+}
+
+func (e *RejectedError) Error() string {
+	if e.Rule == "" {
+		return fmt.Sprintf("хост %q не входит в список разрешенных", e.Host)
+	}
+	return fmt.Sprintf("хост %q запрещен правилом %q", e.Host, e.Rule)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrHostRejected
+}
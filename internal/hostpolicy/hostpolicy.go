@@ -0,0 +1,126 @@
+package hostpolicy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type patternKind int
+
+const (
+	kindExact patternKind = iota
+	kindWildcard
+	kindRegexp
+)
+
+// Pattern — шаблон хоста:
+//   - "example.com" — точное совпадение;
+//   - "*.example.com" — любой поддомен example.com (сам example.com не подходит);
+//   - "~^cdn[0-9]+\.example\.com$" — регулярное выражение, должно совпасть с хостом целиком.
+type Pattern struct {
+	raw  string
+	kind patternKind
+	host string
+	re   *regexp.Regexp
+}
+
+func Parse(s string) (Pattern, error) {
+	raw := strings.TrimSpace(s)
+
+	switch {
+	case raw == "":
+		return Pattern{}, fmt.Errorf("%w: пустой шаблон", ErrInvalidPattern)
+	case strings.HasPrefix(raw, "~"):
+		re, err := regexp.Compile("^(?:" + raw[1:] + ")$")
+		if err != nil {
+			return Pattern{}, fmt.Errorf("%w: %q: %v", ErrInvalidPattern, raw, err)
+		}
+		return Pattern{raw: raw, kind: kindRegexp, re: re}, nil
+	case strings.HasPrefix(raw, "*."):
+		host := normalize(raw[2:])
+		if host == "" || strings.Contains(host, "*") {
+			return Pattern{}, fmt.Errorf("%w: %q", ErrInvalidPattern, raw)
+		}
+		return Pattern{raw: raw, kind: kindWildcard, host: host}, nil
+	default:
+		host := normalize(raw)
+		if strings.Contains(host, "*") {
+			return Pattern{}, fmt.Errorf("%w: %q", ErrInvalidPattern, raw)
+		}
+		return Pattern{raw: raw, kind: kindExact, host: host}, nil
+	}
+}
+
+func MustParse(s string) Pattern {
+	p, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// UnmarshalText позволяет задавать шаблоны через envconfig.
+func (p *Pattern) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func (p Pattern) String() string {
+	return p.raw
+}
+
+func (p Pattern) Match(host string) bool {
+	host = normalize(host)
+
+	switch p.kind {
+	case kindWildcard:
+		return strings.HasSuffix(host, "."+p.host)
+	case kindRegexp:
+		return p.re.MatchString(host)
+	default:
+		return host == p.host
+	}
+}
+
+// Policy проверяет хосты источников: deny список имеет приоритет,
+// при непустом allow списке хост обязан совпасть хотя бы с одним шаблоном.
+type Policy struct {
+	allow []Pattern
+	deny  []Pattern
+}
+
+func New(allow, deny []Pattern) *Policy {
+	return &Policy{
+		allow: allow,
+		deny:  deny,
+	}
+}
+
+func (p *Policy) Check(host string) error {
+	for _, pattern := range p.deny {
+		if pattern.Match(host) {
+			return &RejectedError{Host: host, Rule: pattern.String()}
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return nil
+	}
+
+	for _, pattern := range p.allow {
+		if pattern.Match(host) {
+			return nil
+		}
+	}
+
+	return &RejectedError{Host: host}
+}
+
+func normalize(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,85 @@
+package hostpolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		host     string
+		expected bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "EXAMPLE.com.", true},
+		{"example.com", "www.example.com", false},
+		{"*.example.com", "cdn.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{`~cdn[0-9]+\.example\.com`, "cdn42.example.com", true},
+		{`~cdn[0-9]+\.example\.com`, "cdn42.example.com.evil.org", false},
+		{`~cdn[0-9]+\.example\.com`, "cdn.example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.host, func(t *testing.T) {
+			assert.Equal(t, test.expected, MustParse(test.pattern).Match(test.host))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "  ", "*.", "a.*.com", "~(unclosed"} {
+		_, err := Parse(raw)
+		assert.ErrorIs(t, err, ErrInvalidPattern, "pattern: %q", raw)
+	}
+}
+
+func TestPattern_UnmarshalText(t *testing.T) {
+	var p Pattern
+	require.NoError(t, p.UnmarshalText([]byte("*.example.com")))
+	assert.True(t, p.Match("files.example.com"))
+
+	assert.Error(t, p.UnmarshalText([]byte("~[")))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	allow := []Pattern{MustParse("*.example.com"), MustParse("example.org")}
+	deny := []Pattern{MustParse("secret.example.com")}
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		host   string
+		rule   string
+		ok     bool
+	}{
+		{"no rules", New(nil, nil), "anything.net", "", true},
+		{"allowed wildcard", New(allow, deny), "cdn.example.com", "", true},
+		{"allowed exact", New(allow, deny), "example.org", "", true},
+		{"not in allow list", New(allow, deny), "example.net", "", false},
+		{"denied", New(allow, deny), "secret.example.com", "secret.example.com", false},
+		{"deny only", New(nil, deny), "secret.example.com", "secret.example.com", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(test.host)
+			if test.ok {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrHostRejected)
+			var rejected *RejectedError
+			require.True(t, errors.As(err, &rejected))
+			assert.Equal(t, test.host, rejected.Host)
+			assert.Equal(t, test.rule, rejected.Rule)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package i18n
+
+// catalog — переводы сообщений API. Ключ — исходная фраза на русском, как она
+// написана в коде: текст sentinel-ошибки, формат Errorf или сообщение обработчика.
+// Глаголы %d, %s, %q в переводе идут в том же порядке, что и в исходной фразе.
+var catalog = map[Lang]map[string]string{
+	EN: {
+		// archive_service
+		"отмена контекста": "context canceled",
+		"сервер занят, максимальное количество архивов в процессе достигнуто": "server is busy, the maximum number of archives in progress has been reached",
+		"сервис остановлен":                                          "service is stopped",
+		"сервер перегружен, очередь сборки заполнена":                "server is overloaded, the build queue is full",
+		"превышен лимит файлов в архиве":                             "too many files in the archive",
+		"архив заполнен":                                             "archive is full",
+		"не удалось сохранить архив":                                 "failed to save the archive",
+		"не удалось получить архив":                                  "failed to get the archive",
+		"не удалось создать архив":                                   "failed to build the archive",
+		"не удалось удалить архив":                                   "failed to delete the archive",
+		"не удалось получить список архивов":                         "failed to list archives",
+		"некорректные параметры списка архивов":                      "invalid archive list parameters",
+		"невозможно добавить файл: архив уже собран":                 "cannot add a file: the archive is already built",
+		"невозможно добавить файл: архив не удалось собрать":         "cannot add a file: the archive build failed",
+		"невозможно добавить файл: архив собирается по списку URL":   "cannot add a file: the archive is built from a URL list",
+		"невозможно завершить архив: архив уже собран":               "cannot finalize the archive: the archive is already built",
+		"невозможно завершить архив: архив не удалось собрать":       "cannot finalize the archive: the archive build failed",
+		"невозможно завершить архив: архив собирается по списку URL": "cannot finalize the archive: the archive is built from a URL list",
+		"невозможно завершить архив: в архиве нет файлов":            "cannot finalize the archive: the archive has no files",
+		"невозможно удалить файл: архив уже собран":                  "cannot remove a file: the archive is already built",
+		"невозможно удалить файл: архив не удалось собрать":          "cannot remove a file: the archive build failed",
+		"невозможно удалить файл: архив собирается по списку URL":    "cannot remove a file: the archive is built from a URL list",
+		"файл не найден":                                             "file not found",
+		"неподдерживаемый файл":                                      "unsupported file",
+		"не удалось загрузить файл":                                  "failed to download the file",
+		"некорректный URL файла":                                     "invalid file URL",
+		"хост источника запрещен политикой":                          "source host is not allowed by policy",
+		"файл получен не полностью":                                  "file was received incompletely",
+		"файл превышает допустимый размер":                           "file exceeds the allowed size",
+		"превышен допустимый размер архива":                          "archive exceeds the allowed size",
+		"не удалось создать директорию":                              "failed to create a directory",
+		"не удалось создать файл":                                    "failed to create a file",
+		"не удалось открыть файл":                                    "failed to open a file",
+		"не удалось скопировать файл":                                "failed to copy a file",
+		"не удалось удалить файл/директорию":                         "failed to remove a file or directory",
+		"источник не продолжил скачивание с нужного места":           "source did not resume the download from the requested offset",
+
+		// подробности к ошибкам сервиса
+		"лимит %d байт":                  "limit is %d bytes",
+		"%d байт, лимит %d байт":         "%d bytes, limit is %d bytes",
+		"больше %d байт":                 "more than %d bytes",
+		"получено %d байт":               "received %d bytes",
+		"получено %d из %d байт":         "received %d of %d bytes",
+		"%s (по содержимому: %s)":        "%s (detected from content: %s)",
+		"неизвестный статус %q":          "unknown status %q",
+		"неизвестное поле сортировки %q": "unknown sort field %q",
+		"отрицательный limit":            "negative limit",
+		"начало интервала создания должно быть раньше конца": "created_from must be earlier than created_to",
+
+		// хранилища
+		"архив не найден":                         "archive not found",
+		"архив не может быть nil":                 "archive must not be nil",
+		"ID архива не может быть пустым":          "archive ID must not be empty",
+		"достигнут лимит задач в работе":          "limit of archives in progress reached",
+		"некорректный курсор":                     "invalid cursor",
+		"не удалось сериализовать архив":          "failed to encode the archive",
+		"не удалось прочитать архив из хранилища": "failed to decode the archive from storage",
+		"ошибка Redis":                            "Redis error",
+		"архив слишком часто изменяется параллельно, обновление не удалось": "archive is being modified concurrently too often, update failed",
+
+		// janitor
+		"сборка прервана перезапуском сервиса": "the build was interrupted by a service restart",
+
+		// netguard и hostpolicy
+		"адрес запрещен политикой исходящих соединений": "address is forbidden by the outbound connection policy",
+		"слишком много перенаправлений":                 "too many redirects",
+		"некорректное перенаправление":                  "invalid redirect",
+		"не удалось разрешить хост %q":                  "failed to resolve host %q",
+		"хост %q не входит в список разрешенных":        "host %q is not in the allow list",
+		"хост %q запрещен правилом %q":                  "host %q is denied by rule %q",
+
+		// обработчики API
+		"Некорректный запрос":                                   "Bad request",
+		"не удалось прочитать тело":                             "failed to read the body",
+		"тело должно быть JSON":                                 "the body must be JSON",
+		"список URL пуст":                                       "the URL list is empty",
+		"отсутствует archive_id":                                "archive_id is missing",
+		"поле URL не может быть пустым":                         "the url field must not be empty",
+		"поле filename не может быть пустым":                    "the filename field must not be empty",
+		"limit должен быть положительным числом":                "limit must be a positive number",
+		"%s должен быть в формате RFC3339":                      "%s must be in RFC3339 format",
+		"Архив не найден":                                       "Archive not found",
+		"Архив недоступен для скачивания":                       "Archive is not available for download",
+		"Внутренняя ошибка сервера":                             "Internal server error",
+		"Внутренняя ошибка сервера при кодировании JSON ответа": "Internal server error while encoding the JSON response",
+		"Неверный Content-Type, ожидается application/json":     "Invalid Content-Type, application/json expected",
+		"Файл успешно добавлен к архиву \"%s\"":                 "File successfully added to archive \"%s\"",
+		"Файл \"%s\" удален из архива \"%s\"":                   "File \"%s\" removed from archive \"%s\"",
+	},
+}
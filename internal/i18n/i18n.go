@@ -0,0 +1,212 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang — язык ответов API.
+type Lang string
+
+const (
+	RU Lang = "ru"
+	EN Lang = "en"
+)
+
+// Source — язык, на котором написаны сообщения в коде; он же ключ каталога.
+const Source = RU
+
+// Parse принимает код языка, в том числе с регионом ("en-US").
+func Parse(s string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	switch Lang(primary) {
+	case RU:
+		return RU, true
+	case EN:
+		return EN, true
+	}
+	return "", false
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language с учетом q-весов.
+// Если ни один из языков не поддерживается, возвращается def.
+func Negotiate(header string, def Lang) Lang {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: tag, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return def
+		}
+		if lang, ok := Parse(c.tag); ok {
+			return lang
+		}
+	}
+	return def
+}
+
+type ctxKey struct{}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// FromContext возвращает язык запроса; без него — язык исходных сообщений.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(ctxKey{}).(Lang); ok {
+		return lang
+	}
+	return Source
+}
+
+// Sprintf переводит формат и подставляет аргументы.
+func Sprintf(lang Lang, format string, args ...any) string {
+	if translated, ok := catalog[lang][format]; ok {
+		format = translated
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Translate переводит готовое сообщение, в том числе цепочку ошибок вида
+// "не удалось загрузить файл: HTTP status 404". Сообщение разбирается слева
+// направо: известная фраза (с аргументами %d, %s, %q) заменяется переводом,
+// неизвестный фрагмент до следующего ": " остается как есть.
+func Translate(lang Lang, s string) string {
+	entries := compiled[lang]
+	if len(entries) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for s != "" {
+		if text, rest, ok := translatePrefix(entries, s); ok {
+			b.WriteString(text)
+			s = rest
+			continue
+		}
+		i := strings.Index(s, ": ")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i+2])
+		s = s[i+2:]
+	}
+	return b.String()
+}
+
+type entry struct {
+	re     *regexp.Regexp
+	target string
+}
+
+var compiled = compile(catalog)
+
+func translatePrefix(entries []entry, s string) (string, string, bool) {
+	for _, e := range entries {
+		m := e.re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		// последняя группа — разделитель ": " или конец строки
+		args, sep := m[1:len(m)-1], m[len(m)-1]
+		return fill(e.target, args) + sep, s[len(m[0]):], true
+	}
+	return "", "", false
+}
+
+// fill подставляет аргументы исходной фразы в перевод в том же порядке.
+func fill(target string, args []string) string {
+	var b strings.Builder
+	k := 0
+	for i := 0; i < len(target); i++ {
+		if target[i] == '%' && i+1 < len(target) && strings.IndexByte("dsqv", target[i+1]) >= 0 && k < len(args) {
+			b.WriteString(args[k])
+			k++
+			i++
+			continue
+		}
+		b.WriteByte(target[i])
+	}
+	return b.String()
+}
+
+// %s и %v не захватывают ": ", иначе аргумент проглотит предыдущую фразу цепочки.
+var verbPatterns = map[byte]string{
+	'd': `(-?\d+)`,
+	's': `((?:[^:]|:[^ ])+?)`,
+	'v': `((?:[^:]|:[^ ])+?)`,
+	'q': `("(?:[^"\\]|\\.)*")`,
+}
+
+// compile превращает фразы каталога в регулярные выражения. Длинные фразы
+// проверяются первыми, чтобы "не удалось создать архив" не перекрывал более точные.
+func compile(catalog map[Lang]map[string]string) map[Lang][]entry {
+	result := make(map[Lang][]entry, len(catalog))
+	for lang, messages := range catalog {
+		sources := make([]string, 0, len(messages))
+		for source := range messages {
+			sources = append(sources, source)
+		}
+		sort.Slice(sources, func(i, j int) bool {
+			if len(sources[i]) != len(sources[j]) {
+				return len(sources[i]) > len(sources[j])
+			}
+			return sources[i] < sources[j]
+		})
+
+		entries := make([]entry, 0, len(sources))
+		for _, source := range sources {
+			var pattern strings.Builder
+			literal := 0
+			for i := 0; i < len(source); i++ {
+				if source[i] != '%' || i+1 >= len(source) {
+					continue
+				}
+				verb, ok := verbPatterns[source[i+1]]
+				if !ok {
+					continue
+				}
+				pattern.WriteString(regexp.QuoteMeta(source[literal:i]))
+				pattern.WriteString(verb)
+				literal = i + 2
+				i++
+			}
+			pattern.WriteString(regexp.QuoteMeta(source[literal:]))
+
+			entries = append(entries, entry{
+				re:     regexp.MustCompile("^(?:" + pattern.String() + ")(: |$)"),
+				target: messages[source],
+			})
+		}
+		result[lang] = entries
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header   string
+		def      Lang
+		expected Lang
+	}{
+		{"", RU, RU},
+		{"", EN, EN},
+		{"en", RU, EN},
+		{"en-US,en;q=0.9", RU, EN},
+		{"ru-RU", EN, RU},
+		{"de-DE,en;q=0.5,ru;q=0.8", EN, RU},
+		{"de, fr;q=0.9", EN, EN},
+		{"en;q=0, ru", EN, RU},
+		{"*", EN, EN},
+		{"en;q=abc", RU, RU},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			assert.Equal(t, test.expected, Negotiate(test.header, test.def))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"некорректный URL файла", "invalid file URL"},
+		{"не удалось загрузить файл: HTTP status 404", "failed to download the file: HTTP status 404"},
+		{"не удалось скопировать файл: файл получен не полностью: получено 5 из 10 байт", "failed to copy a file: file was received incompletely: received 5 of 10 bytes"},
+		{"файл превышает допустимый размер: 300 байт, лимит 100 байт", "file exceeds the allowed size: 300 bytes, limit is 100 bytes"},
+		{"неподдерживаемый файл: text/html (по содержимому: text/plain)", "unsupported file: text/html (detected from content: text/plain)"},
+		{`хост источника запрещен политикой: хост "evil.com" запрещен правилом "*.com"`, `source host is not allowed by policy: host "evil.com" is denied by rule "*.com"`},
+		{"невозможно добавить файл: архив уже собран", "cannot add a file: the archive is already built"},
+		{"Внутренняя ошибка сервера при кодировании JSON ответа", "Internal server error while encoding the JSON response"},
+		{"Некорректный запрос: created_from должен быть в формате RFC3339", "Bad request: created_from must be in RFC3339 format"},
+		{"файл не найден: a.pdf", "file not found: a.pdf"},
+		{"неизвестная фраза: архив заполнен", "неизвестная фраза: archive is full"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			assert.Equal(t, test.expected, Translate(EN, test.source))
+			assert.Equal(t, test.source, Translate(RU, test.source))
+		})
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	assert.Equal(t, `File successfully added to archive "42"`, Sprintf(EN, "Файл успешно добавлен к архиву \"%s\"", "42"))
+	assert.Equal(t, `Файл успешно добавлен к архиву "42"`, Sprintf(RU, "Файл успешно добавлен к архиву \"%s\"", "42"))
+}
+
+func TestCatalog_Verbs(t *testing.T) {
+	for source, target := range catalog[EN] {
+		assert.Equal(t, verbs(source), verbs(target), "глаголы перевода не совпадают: %q", source)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Source, FromContext(context.Background()))
+	assert.Equal(t, EN, FromContext(WithLang(context.Background(), EN)))
+}
+
+func verbs(s string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] == '%' {
+			b.WriteByte(s[i+1])
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,334 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/infra/listing"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+var _ infra.Database = (*boltDB)(nil)
+
+var archivesBucket = []byte("archives")
+
+// boltDB хранит архивы в файле bbolt: ключ — ID, значение — JSON модели.
+type boltDB struct {
+	logger *zap.Logger
+	db     *bolt.DB
+	ttl    time.Duration
+}
+
+// New открывает (или создает) файл хранилища. Возвращаемое значение реализует io.Closer.
+func New(log *zap.Logger, path string, ttl time.Duration) (infra.Database, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpen, err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOpen, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(archivesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %v", ErrOpen, err)
+	}
+
+	return &boltDB{
+		logger: log,
+		db:     db,
+		ttl:    ttl,
+	}, nil
+}
+
+func (db *boltDB) Close() error {
+	return db.db.Close()
+}
+
+func (db *boltDB) SaveArchive(ctx context.Context, archive *models.Archive) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if archive == nil {
+		return ErrArchiveNil
+	}
+
+	if archive.ID == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEncode, err)
+	}
+
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(archivesBucket).Put([]byte(archive.ID), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
+	return nil
+}
+
+func (db *boltDB) InsertArchive(ctx context.Context, archive *models.Archive, maxInProcess int) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if archive == nil {
+		return ErrArchiveNil
+	}
+
+	if archive.ID == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEncode, err)
+	}
+
+	// bbolt допускает одну пишущую транзакцию, поэтому подсчет и запись не пересекаются с другими
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		count, err := db.countInProcess(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		if count >= maxInProcess {
+			return ErrLimitReached
+		}
+		return tx.Bucket(archivesBucket).Put([]byte(archive.ID), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
+	return nil
+}
+
+func (db *boltDB) GetArchive(ctx context.Context, id string) (*models.Archive, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrArchiveIDEmpty
+	}
+
+	var archive *models.Archive
+	err := db.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(archivesBucket).Get([]byte(id))
+		if data == nil {
+			return ErrArchiveNotFound
+		}
+
+		var err error
+		archive, err = decode(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func (db *boltDB) UpdateArchive(ctx context.Context, id string, fn func(*models.Archive) error) (*models.Archive, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrArchiveIDEmpty
+	}
+
+	var archive *models.Archive
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archivesBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrArchiveNotFound
+		}
+
+		var err error
+		archive, err = decode(data)
+		if err != nil {
+			return err
+		}
+		if err := fn(archive); err != nil {
+			return err
+		}
+		archive.ID = id
+
+		data, err = json.Marshal(archive)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrEncode, err)
+		}
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.logger.Info("архив обновлен", zap.String("archive_id", id))
+	return archive, nil
+}
+
+func (db *boltDB) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	var archives []*models.Archive
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(archivesBucket).ForEach(func(_, data []byte) error {
+			archive, err := decode(data)
+			if err != nil {
+				return err
+			}
+			if listing.Match(archive, query) {
+				archives = append(archives, archive)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return listing.Page(archives, query)
+}
+
+func (db *boltDB) CountArchivesInProcess(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	var count int
+	err := db.db.View(func(tx *bolt.Tx) error {
+		var err error
+		count, err = db.countInProcess(tx, time.Now())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (db *boltDB) countInProcess(tx *bolt.Tx, now time.Time) (int, error) {
+	count := 0
+	err := tx.Bucket(archivesBucket).ForEach(func(_, data []byte) error {
+		archive, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if archive.Status == models.ArchiveStatusBuilding ||
+			archive.Status == models.ArchiveStatusEmpty {
+			if now.Sub(archive.UpdatedAt) > db.ttl {
+				return nil
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (db *boltDB) DeleteArchive(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archivesBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrArchiveNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+	if err != nil {
+		return err
+	}
+
+	db.logger.Info("архив удален", zap.String("archive_id", id))
+	return nil
+}
+
+func (db *boltDB) ListExpired(ctx context.Context, retention map[models.ArchiveStatus]time.Duration) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	now := time.Now()
+	var ids []string
+
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(archivesBucket).ForEach(func(key, data []byte) error {
+			archive, err := decode(data)
+			if err != nil {
+				return err
+			}
+			ttl, ok := retention[archive.Status]
+			if !ok || ttl <= 0 {
+				return nil
+			}
+			if now.Sub(archive.UpdatedAt) > ttl {
+				ids = append(ids, string(key))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func decode(data []byte) (*models.Archive, error) {
+	var archive models.Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return &archive, nil
+}
@@ -0,0 +1,52 @@
+package boltdb
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/sunr3d/05-08-2025/internal/infra/dbtest"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+func TestBoltDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, ttl time.Duration) infra.Database {
+		db, err := New(zaptest.NewLogger(t), filepath.Join(t.TempDir(), "archives.db"), ttl)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.(io.Closer).Close() })
+		return db
+	})
+}
+
+func TestBoltDB_SurvivesReopen(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	path := filepath.Join(t.TempDir(), "nested", "archives.db")
+	ctx := context.Background()
+
+	db, err := New(logger, path, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, db.SaveArchive(ctx, &models.Archive{
+		ID:        "a1",
+		Status:    models.ArchiveStatusReady,
+		Files:     []string{"a.pdf"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, db.(io.Closer).Close())
+
+	db, err = New(logger, path, time.Hour)
+	require.NoError(t, err)
+	defer db.(io.Closer).Close()
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, got.Status)
+	assert.Equal(t, []string{"a.pdf"}, got.Files)
+}
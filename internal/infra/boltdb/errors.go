@@ -0,0 +1,20 @@
+package boltdb
+
+import (
+	"errors"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+)
+
+var (
+	ErrArchiveNotFound = infra.ErrArchiveNotFound
+	ErrArchiveNil      = infra.ErrArchiveNil
+	ErrArchiveIDEmpty  = infra.ErrArchiveIDEmpty
+	ErrContextDone     = infra.ErrContextDone
+	ErrLimitReached    = infra.ErrLimitReached
+	ErrInvalidCursor   = infra.ErrInvalidCursor
+
+	ErrOpen   = errors.New("не удалось открыть файл хранилища")
+	ErrEncode = errors.New("не удалось сериализовать архив")
+	ErrDecode = errors.New("не удалось прочитать архив из хранилища")
+)
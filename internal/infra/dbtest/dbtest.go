@@ -0,0 +1,565 @@
+// Package dbtest содержит общие тесты для реализаций infra.Database:
+// каждое хранилище должно вести себя так же, как in-memory.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// Factory создает пустое хранилище с заданным TTL для задач в работе.
+type Factory func(t *testing.T, ttl time.Duration) infra.Database
+
+// Run прогоняет все общие тесты для хранилища.
+func Run(t *testing.T, newDB Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, newDB Factory)
+	}{
+		{"SaveAndGet", testSaveAndGet},
+		{"Overwrite", testOverwrite},
+		{"Validation", testValidation},
+		{"NotFound", testNotFound},
+		{"Delete", testDelete},
+		{"Isolation", testIsolation},
+		{"Update", testUpdate},
+		{"UpdateRejected", testUpdateRejected},
+		{"ConcurrentUpdate", testConcurrentUpdate},
+		{"InsertLimit", testInsertLimit},
+		{"ConcurrentInsert", testConcurrentInsert},
+		{"CountArchivesInProcess", testCountArchivesInProcess},
+		{"ListExpired", testListExpired},
+		{"ListArchives", testListArchives},
+		{"ListArchivesPaging", testListArchivesPaging},
+		{"ContextCanceled", testContextCanceled},
+		{"Concurrency", testConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newDB)
+		})
+	}
+}
+
+func newArchive(id string, status models.ArchiveStatus, age time.Duration) *models.Archive {
+	ts := time.Now().Add(-age).UTC().Truncate(time.Millisecond)
+	return &models.Archive{
+		ID:        id,
+		Status:    status,
+		Files:     []string{"a.pdf", "b.jpg"},
+		CreatedAt: ts,
+		UpdatedAt: ts,
+		Errors:    []string{"http://example.com/c.pdf - не удалось загрузить файл"},
+		Attempts:  map[string]int{"http://example.com/a.pdf": 2},
+		Entries: []models.FileEntry{
+			{
+				URL:        "http://example.com/a.pdf",
+				Name:       "a.pdf",
+				Size:       1024,
+				MIME:       "application/pdf",
+				SHA256:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+				Status:     models.FileStatusOK,
+				Attempts:   2,
+				StartedAt:  ts,
+				FinishedAt: ts,
+			},
+			{URL: "http://example.com/c.pdf", Status: models.FileStatusFailed, ErrorCode: "download_failed", Error: "не удалось загрузить файл"},
+		},
+	}
+}
+
+func testSaveAndGet(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	archive := newArchive("a1", models.ArchiveStatusReady, 0)
+	require.NoError(t, db.SaveArchive(ctx, archive))
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, archive.ID, got.ID)
+	assert.Equal(t, archive.Status, got.Status)
+	assert.Equal(t, archive.Files, got.Files)
+	assert.Equal(t, archive.Errors, got.Errors)
+	assert.Equal(t, archive.Attempts, got.Attempts)
+	assert.Equal(t, archive.Entries, got.Entries)
+	assert.True(t, archive.CreatedAt.Equal(got.CreatedAt))
+	assert.True(t, archive.UpdatedAt.Equal(got.UpdatedAt))
+}
+
+func testOverwrite(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	archive := newArchive("a1", models.ArchiveStatusBuilding, 0)
+	require.NoError(t, db.SaveArchive(ctx, archive))
+
+	updated := newArchive("a1", models.ArchiveStatusReady, 0)
+	updated.Files = []string{"only.pdf"}
+	require.NoError(t, db.SaveArchive(ctx, updated))
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, got.Status)
+	assert.Equal(t, []string{"only.pdf"}, got.Files)
+}
+
+func testValidation(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, db.SaveArchive(ctx, nil), infra.ErrArchiveNil)
+	assert.ErrorIs(t, db.SaveArchive(ctx, &models.Archive{}), infra.ErrArchiveIDEmpty)
+
+	_, err := db.GetArchive(ctx, "")
+	assert.ErrorIs(t, err, infra.ErrArchiveIDEmpty)
+	assert.ErrorIs(t, db.DeleteArchive(ctx, ""), infra.ErrArchiveIDEmpty)
+
+	_, err = db.UpdateArchive(ctx, "", func(*models.Archive) error { return nil })
+	assert.ErrorIs(t, err, infra.ErrArchiveIDEmpty)
+
+	assert.ErrorIs(t, db.InsertArchive(ctx, nil, 1), infra.ErrArchiveNil)
+	assert.ErrorIs(t, db.InsertArchive(ctx, &models.Archive{}, 1), infra.ErrArchiveIDEmpty)
+}
+
+func testNotFound(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	_, err := db.GetArchive(ctx, "missing")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+	assert.ErrorIs(t, db.DeleteArchive(ctx, "missing"), infra.ErrArchiveNotFound)
+
+	_, err = db.UpdateArchive(ctx, "missing", func(*models.Archive) error {
+		t.Error("fn не должна вызываться для отсутствующего архива")
+		return nil
+	})
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+func testDelete(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveArchive(ctx, newArchive("a1", models.ArchiveStatusReady, 0)))
+	require.NoError(t, db.DeleteArchive(ctx, "a1"))
+
+	_, err := db.GetArchive(ctx, "a1")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+// testIsolation проверяет, что хранилище не разделяет память с вызывающим кодом.
+func testIsolation(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	archive := newArchive("a1", models.ArchiveStatusBuilding, 0)
+	require.NoError(t, db.SaveArchive(ctx, archive))
+	archive.Files[0] = "changed.pdf"
+	archive.Attempts["http://example.com/a.pdf"] = 100
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	got.Files = append(got.Files, "extra.pdf")
+	got.Errors[0] = "changed"
+	got.Entries[0].Status = models.FileStatusFailed
+	got.Status = models.ArchiveStatusReady
+
+	again, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.pdf", "b.jpg"}, again.Files)
+	assert.Equal(t, []string{"http://example.com/c.pdf - не удалось загрузить файл"}, again.Errors)
+	assert.Equal(t, map[string]int{"http://example.com/a.pdf": 2}, again.Attempts)
+	assert.Equal(t, models.FileStatusOK, again.Entries[0].Status)
+	assert.Equal(t, models.ArchiveStatusBuilding, again.Status)
+
+	updated, err := db.UpdateArchive(ctx, "a1", func(a *models.Archive) error {
+		a.Files = append(a.Files, "c.pdf")
+		return nil
+	})
+	require.NoError(t, err)
+	updated.Files[0] = "changed.pdf"
+
+	again, err = db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.pdf", "b.jpg", "c.pdf"}, again.Files)
+}
+
+func testUpdate(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveArchive(ctx, newArchive("a1", models.ArchiveStatusBuilding, time.Minute)))
+
+	updatedAt := time.Now().UTC().Truncate(time.Millisecond)
+	updated, err := db.UpdateArchive(ctx, "a1", func(a *models.Archive) error {
+		a.Status = models.ArchiveStatusReady
+		a.Files = append(a.Files, "c.pdf")
+		a.UpdatedAt = updatedAt
+		a.ID = "другой"
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a1", updated.ID)
+	assert.Equal(t, models.ArchiveStatusReady, updated.Status)
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, got.Status)
+	assert.Equal(t, []string{"a.pdf", "b.jpg", "c.pdf"}, got.Files)
+	assert.True(t, updatedAt.Equal(got.UpdatedAt))
+
+	_, err = db.GetArchive(ctx, "другой")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+
+	count, err := db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testUpdateRejected(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveArchive(ctx, newArchive("a1", models.ArchiveStatusBuilding, 0)))
+
+	errRejected := errors.New("отклонено")
+	_, err := db.UpdateArchive(ctx, "a1", func(a *models.Archive) error {
+		a.Status = models.ArchiveStatusFailed
+		a.Files = nil
+		return errRejected
+	})
+	assert.ErrorIs(t, err, errRejected)
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, got.Status)
+	assert.Equal(t, []string{"a.pdf", "b.jpg"}, got.Files)
+}
+
+// testConcurrentUpdate проверяет, что параллельные UpdateArchive не теряют изменений
+// и проверка внутри fn видит результат предыдущих обновлений.
+func testConcurrentUpdate(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	archive := newArchive("a1", models.ArchiveStatusBuilding, 0)
+	archive.Files = nil
+	require.NoError(t, db.SaveArchive(ctx, archive))
+
+	const (
+		n     = 20
+		limit = 5
+	)
+	errFull := errors.New("архив заполнен")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := db.UpdateArchive(ctx, "a1", func(a *models.Archive) error {
+				if len(a.Files) >= limit {
+					return errFull
+				}
+				a.Files = append(a.Files, fmt.Sprintf("f%02d.pdf", i))
+				return nil
+			})
+			if err == nil {
+				mu.Lock()
+				added++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, errFull)
+		}()
+	}
+	wg.Wait()
+
+	got, err := db.GetArchive(ctx, "a1")
+	require.NoError(t, err)
+	assert.Len(t, got.Files, limit)
+	assert.Equal(t, limit, added)
+}
+
+func testInsertLimit(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	// готовые и просроченные задачи слот не занимают
+	require.NoError(t, db.SaveArchive(ctx, newArchive("ready", models.ArchiveStatusReady, 0)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("stale", models.ArchiveStatusBuilding, 2*time.Hour)))
+
+	require.NoError(t, db.InsertArchive(ctx, newArchive("a1", models.ArchiveStatusEmpty, 0), 2))
+	require.NoError(t, db.InsertArchive(ctx, newArchive("a2", models.ArchiveStatusBuilding, 0), 2))
+	assert.ErrorIs(t, db.InsertArchive(ctx, newArchive("a3", models.ArchiveStatusEmpty, 0), 2), infra.ErrLimitReached)
+
+	_, err := db.GetArchive(ctx, "a3")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+
+	got, err := db.GetArchive(ctx, "a2")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, got.Status)
+
+	// завершенная задача освобождает слот
+	_, err = db.UpdateArchive(ctx, "a1", func(a *models.Archive) error {
+		a.Status = models.ArchiveStatusReady
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NoError(t, db.InsertArchive(ctx, newArchive("a3", models.ArchiveStatusEmpty, 0), 2))
+
+	assert.ErrorIs(t, db.InsertArchive(ctx, newArchive("a4", models.ArchiveStatusEmpty, 0), 0), infra.ErrLimitReached)
+}
+
+// testConcurrentInsert проверяет, что лимит не превышается при одновременных вставках.
+func testConcurrentInsert(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	const (
+		n     = 100
+		limit = 7
+	)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status := models.ArchiveStatusEmpty
+			if i%2 == 0 {
+				status = models.ArchiveStatusBuilding
+			}
+			err := db.InsertArchive(ctx, newArchive(fmt.Sprintf("a%03d", i), status, 0), limit)
+			if err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, infra.ErrLimitReached)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit, admitted)
+
+	count, err := db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, limit, count)
+}
+
+func testCountArchivesInProcess(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveArchive(ctx, newArchive("empty", models.ArchiveStatusEmpty, 0)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("building", models.ArchiveStatusBuilding, time.Minute)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("ready", models.ArchiveStatusReady, 0)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("failed", models.ArchiveStatusFailed, 0)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("stale", models.ArchiveStatusBuilding, 2*time.Hour)))
+
+	count, err := db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func testListExpired(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, db.SaveArchive(ctx, newArchive("ready-old", models.ArchiveStatusReady, 2*time.Hour)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("ready-new", models.ArchiveStatusReady, time.Minute)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("failed-old", models.ArchiveStatusFailed, 2*time.Hour)))
+	require.NoError(t, db.SaveArchive(ctx, newArchive("empty-old", models.ArchiveStatusEmpty, 2*time.Hour)))
+
+	ids, err := db.ListExpired(ctx, map[models.ArchiveStatus]time.Duration{
+		models.ArchiveStatusReady:  time.Hour,
+		models.ArchiveStatusFailed: 0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ready-old"}, ids)
+}
+
+func ids(archives []*models.Archive) []string {
+	ids := make([]string, len(archives))
+	for i, archive := range archives {
+		ids[i] = archive.ID
+	}
+	return ids
+}
+
+func testListArchives(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	// созданы по порядку a → e, обновлены в обратном
+	base := time.Now().Add(-10 * time.Minute).UTC().Truncate(time.Millisecond)
+	for i, status := range []models.ArchiveStatus{
+		models.ArchiveStatusReady,
+		models.ArchiveStatusFailed,
+		models.ArchiveStatusBuilding,
+		models.ArchiveStatusReady,
+		models.ArchiveStatusEmpty,
+	} {
+		archive := newArchive(string(rune('a'+i)), status, 0)
+		archive.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		archive.UpdatedAt = base.Add(time.Duration(10-i) * time.Second)
+		require.NoError(t, db.SaveArchive(ctx, archive))
+	}
+
+	tests := []struct {
+		name     string
+		query    models.ArchiveQuery
+		expected []string
+	}{
+		{"все по created_at", models.ArchiveQuery{}, []string{"a", "b", "c", "d", "e"}},
+		{"по убыванию", models.ArchiveQuery{Desc: true}, []string{"e", "d", "c", "b", "a"}},
+		{"по updated_at", models.ArchiveQuery{SortBy: models.SortByUpdatedAt}, []string{"e", "d", "c", "b", "a"}},
+		{
+			"по статусам",
+			models.ArchiveQuery{Statuses: []models.ArchiveStatus{models.ArchiveStatusReady, models.ArchiveStatusEmpty}},
+			[]string{"a", "d", "e"},
+		},
+		{
+			"по интервалу создания",
+			models.ArchiveQuery{CreatedFrom: base.Add(time.Minute), CreatedTo: base.Add(3 * time.Minute)},
+			[]string{"b", "c"},
+		},
+		{
+			"ничего не найдено",
+			models.ArchiveQuery{Statuses: []models.ArchiveStatus{models.ArchiveStatusFailed}, CreatedFrom: base.Add(time.Hour)},
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := db.ListArchives(ctx, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(page.Archives))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+
+	_, err := db.ListArchives(ctx, models.ArchiveQuery{Cursor: "не курсор"})
+	assert.ErrorIs(t, err, infra.ErrInvalidCursor)
+}
+
+// testListArchivesPaging проходит все страницы и проверяет, что архивы не теряются
+// и не повторяются, даже если у нескольких архивов одинаковое время создания.
+func testListArchivesPaging(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Minute).UTC().Truncate(time.Millisecond)
+	var expected []string
+	for i := 0; i < 7; i++ {
+		archive := newArchive(fmt.Sprintf("a%d", i), models.ArchiveStatusReady, 0)
+		archive.CreatedAt = base.Add(time.Duration(i/2) * time.Second)
+		require.NoError(t, db.SaveArchive(ctx, archive))
+		expected = append(expected, archive.ID)
+	}
+
+	for _, desc := range []bool{false, true} {
+		var got []string
+		query := models.ArchiveQuery{Limit: 3, Desc: desc}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 10, "курсор не продвигается")
+
+			page, err := db.ListArchives(ctx, query)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Archives), 3)
+			got = append(got, ids(page.Archives)...)
+
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		want := slices.Clone(expected)
+		if desc {
+			slices.Reverse(want)
+		}
+		assert.Equal(t, want, got)
+	}
+}
+
+func testContextCanceled(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, db.SaveArchive(ctx, newArchive("a1", models.ArchiveStatusReady, 0)), infra.ErrContextDone)
+	assert.ErrorIs(t, db.InsertArchive(ctx, newArchive("a1", models.ArchiveStatusEmpty, 0), 1), infra.ErrContextDone)
+
+	_, err := db.GetArchive(ctx, "a1")
+	assert.ErrorIs(t, err, infra.ErrContextDone)
+
+	_, err = db.CountArchivesInProcess(ctx)
+	assert.ErrorIs(t, err, infra.ErrContextDone)
+
+	assert.ErrorIs(t, db.DeleteArchive(ctx, "a1"), infra.ErrContextDone)
+
+	_, err = db.UpdateArchive(ctx, "a1", func(*models.Archive) error { return nil })
+	assert.ErrorIs(t, err, infra.ErrContextDone)
+
+	_, err = db.ListExpired(ctx, map[models.ArchiveStatus]time.Duration{models.ArchiveStatusReady: time.Hour})
+	assert.ErrorIs(t, err, infra.ErrContextDone)
+
+	_, err = db.ListArchives(ctx, models.ArchiveQuery{})
+	assert.ErrorIs(t, err, infra.ErrContextDone)
+}
+
+func testConcurrency(t *testing.T, newDB Factory) {
+	db := newDB(t, time.Hour)
+	ctx := context.Background()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			id := fmt.Sprintf("a%02d", i)
+			assert.NoError(t, db.SaveArchive(ctx, newArchive(id, models.ArchiveStatusBuilding, 0)))
+			_, err := db.GetArchive(ctx, id)
+			assert.NoError(t, err)
+			_, err = db.CountArchivesInProcess(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	count, err := db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, n, count)
+
+	ids, err := db.ListExpired(ctx, map[models.ArchiveStatus]time.Duration{models.ArchiveStatusBuilding: time.Nanosecond})
+	require.NoError(t, err)
+	sort.Strings(ids)
+	assert.Len(t, ids, n)
+	assert.Equal(t, "a00", ids[0])
+}
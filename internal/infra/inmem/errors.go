@@ -1,10 +1,12 @@
 package inmem
 
-import "errors"
+import "github.com/sunr3d/05-08-2025/internal/interfaces/infra"
 
 var (
-	ErrArchiveNotFound = errors.New("архив не найден")
-	ErrArchiveNil      = errors.New("архив не может быть nil")
-	ErrArchiveIDEmpty  = errors.New("ID архива не может быть пустым")
-	ErrContextDone     = errors.New("отмена контекста")
+	ErrArchiveNotFound = infra.ErrArchiveNotFound
+	ErrArchiveNil      = infra.ErrArchiveNil
+	ErrArchiveIDEmpty  = infra.ErrArchiveIDEmpty
+	ErrContextDone     = infra.ErrContextDone
+	ErrLimitReached    = infra.ErrLimitReached
+	ErrInvalidCursor   = infra.ErrInvalidCursor
 )
@@ -8,6 +8,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/sunr3d/05-08-2025/internal/infra/listing"
 	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
 	"github.com/sunr3d/05-08-2025/models"
 )
@@ -47,7 +48,35 @@ func (db *inmemDB) SaveArchive(ctx context.Context, archive *models.Archive) err
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	db.db[archive.ID] = archive
+	db.db[archive.ID] = archive.Clone()
+	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
+
+	return nil
+}
+
+func (db *inmemDB) InsertArchive(ctx context.Context, archive *models.Archive, maxInProcess int) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if archive == nil {
+		return ErrArchiveNil
+	}
+
+	if archive.ID == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.countInProcess(time.Now()) >= maxInProcess {
+		return ErrLimitReached
+	}
+
+	db.db[archive.ID] = archive.Clone()
 	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
 
 	return nil
@@ -72,35 +101,88 @@ func (db *inmemDB) GetArchive(ctx context.Context, id string) (*models.Archive,
 		return nil, ErrArchiveNotFound
 	}
 
-	return archive, nil
+	return archive.Clone(), nil
 }
 
-func (db *inmemDB) CountArchivesInProcess(ctx context.Context) (int, error) {
+func (db *inmemDB) UpdateArchive(ctx context.Context, id string, fn func(*models.Archive) error) (*models.Archive, error) {
 	select {
 	case <-ctx.Done():
-		return 0, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
 	default:
 	}
 
+	if id == "" {
+		return nil, ErrArchiveIDEmpty
+	}
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	stored, exists := db.db[id]
+	if !exists {
+		return nil, ErrArchiveNotFound
+	}
+
+	archive := stored.Clone()
+	if err := fn(archive); err != nil {
+		return nil, err
+	}
+	archive.ID = id
+
+	db.db[id] = archive
+	db.logger.Info("архив обновлен", zap.String("archive_id", id))
+
+	return archive.Clone(), nil
+}
+
+func (db *inmemDB) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	db.mu.RLock()
+	archives := make([]*models.Archive, 0, len(db.db))
+	for _, archive := range db.db {
+		if listing.Match(archive, query) {
+			archives = append(archives, archive.Clone())
+		}
+	}
+	db.mu.RUnlock()
+
+	return listing.Page(archives, query)
+}
+
+func (db *inmemDB) CountArchivesInProcess(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.countInProcess(time.Now()), nil
+}
+
+// countInProcess считает задачи в работе. Вызывается под db.mu.
+func (db *inmemDB) countInProcess(now time.Time) int {
 	count := 0
-	now := time.Now()
 
-	for id, archive := range db.db {
+	// просроченные задачи не занимают слот; удаляет их janitor вместе с файлами
+	for _, archive := range db.db {
 		if archive.Status == models.ArchiveStatusBuilding ||
 			archive.Status == models.ArchiveStatusEmpty {
 			if now.Sub(archive.UpdatedAt) > db.ttl {
-				delete(db.db, id)
-				db.logger.Info("архив удален по TTL", zap.String("archive_id", id))
 				continue
 			}
 			count++
 		}
 	}
 
-	return count, nil
+	return count
 }
 
 func (db *inmemDB) DeleteArchive(ctx context.Context, id string) error {
@@ -126,3 +208,28 @@ func (db *inmemDB) DeleteArchive(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (db *inmemDB) ListExpired(ctx context.Context, retention map[models.ArchiveStatus]time.Duration) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	now := time.Now()
+	var ids []string
+	for id, archive := range db.db {
+		ttl, ok := retention[archive.Status]
+		if !ok || ttl <= 0 {
+			continue
+		}
+		if now.Sub(archive.UpdatedAt) > ttl {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
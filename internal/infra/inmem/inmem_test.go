@@ -0,0 +1,17 @@
+package inmem
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/sunr3d/05-08-2025/internal/infra/dbtest"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+)
+
+func TestInmemDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, ttl time.Duration) infra.Database {
+		return New(zaptest.NewLogger(t), ttl)
+	})
+}
@@ -0,0 +1,142 @@
+// Package listing фильтрует, сортирует и режет на страницы список архивов
+// одинаково для всех хранилищ.
+package listing
+
+import (
+	"cmp"
+	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// cursor — позиция последнего отданного архива: значение поля сортировки и ID.
+type cursor struct {
+	key int64
+	id  string
+}
+
+// Cursor — разобранный курсор страницы: позиция последнего отданного архива в порядке query.
+type Cursor struct {
+	at      cursor
+	sortBy  models.ArchiveSortField
+	compare func(a, b cursor) int
+}
+
+// ParseCursor разбирает query.Cursor. Для первой страницы возвращает nil.
+func ParseCursor(query models.ArchiveQuery) (*Cursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+	c, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{at: c, sortBy: query.SortBy, compare: comparator(query.Desc)}, nil
+}
+
+// Key — значение поля сортировки курсора в наносекундах.
+func (c *Cursor) Key() int64 {
+	return c.at.key
+}
+
+// Passed сообщает, что архив уже отдан на предыдущих страницах.
+func (c *Cursor) Passed(archive *models.Archive) bool {
+	return c.compare(position(archive, c.sortBy), c.at) <= 0
+}
+
+// Page применяет query к archives. Срез archives переупорядочивается.
+func Page(archives []*models.Archive, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	after, err := ParseCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := slices.DeleteFunc(archives, func(a *models.Archive) bool {
+		return !Match(a, query)
+	})
+
+	compare := comparator(query.Desc)
+	slices.SortFunc(matched, func(a, b *models.Archive) int {
+		return compare(position(a, query.SortBy), position(b, query.SortBy))
+	})
+
+	if after != nil {
+		start, _ := slices.BinarySearchFunc(matched, after, func(a *models.Archive, c *Cursor) int {
+			if c.Passed(a) {
+				return -1
+			}
+			return 1
+		})
+		matched = matched[start:]
+	}
+
+	page := &models.ArchivePage{Archives: matched}
+	if query.Limit > 0 && len(matched) > query.Limit {
+		page.Archives = matched[:query.Limit]
+		page.NextCursor = encodeCursor(position(page.Archives[query.Limit-1], query.SortBy))
+	}
+
+	return page, nil
+}
+
+func comparator(desc bool) func(a, b cursor) int {
+	compare := func(a, b cursor) int {
+		if c := cmp.Compare(a.key, b.key); c != 0 {
+			return c
+		}
+		return strings.Compare(a.id, b.id)
+	}
+	if desc {
+		return func(a, b cursor) int { return compare(b, a) }
+	}
+	return compare
+}
+
+// Match проверяет фильтры query без учета сортировки и страницы.
+func Match(archive *models.Archive, query models.ArchiveQuery) bool {
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, archive.Status) {
+		return false
+	}
+	if !query.CreatedFrom.IsZero() && archive.CreatedAt.Before(query.CreatedFrom) {
+		return false
+	}
+	if !query.CreatedTo.IsZero() && !archive.CreatedAt.Before(query.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func position(archive *models.Archive, sortBy models.ArchiveSortField) cursor {
+	ts := archive.CreatedAt
+	if sortBy == models.SortByUpdatedAt {
+		ts = archive.UpdatedAt
+	}
+	return cursor{key: ts.UnixNano(), id: archive.ID}
+}
+
+func encodeCursor(c cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.key, 10) + ":" + c.id))
+}
+
+func decodeCursor(value string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, infra.ErrInvalidCursor
+	}
+
+	key, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return cursor{}, infra.ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return cursor{}, infra.ErrInvalidCursor
+	}
+
+	return cursor{key: nanos, id: id}, nil
+}
@@ -0,0 +1,21 @@
+package redisdb
+
+import (
+	"errors"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+)
+
+var (
+	ErrArchiveNotFound = infra.ErrArchiveNotFound
+	ErrArchiveNil      = infra.ErrArchiveNil
+	ErrArchiveIDEmpty  = infra.ErrArchiveIDEmpty
+	ErrContextDone     = infra.ErrContextDone
+	ErrLimitReached    = infra.ErrLimitReached
+	ErrInvalidCursor   = infra.ErrInvalidCursor
+
+	ErrEncode   = errors.New("не удалось сериализовать архив")
+	ErrDecode   = errors.New("не удалось прочитать архив из хранилища")
+	ErrRedis    = errors.New("ошибка Redis")
+	ErrConflict = errors.New("архив слишком часто изменяется параллельно, обновление не удалось")
+)
@@ -0,0 +1,639 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/infra/listing"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+var _ infra.Database = (*redisDB)(nil)
+
+// statuses — статусы, для которых ведется индекс. Порядок задает KEYS в скриптах.
+var statuses = []models.ArchiveStatus{
+	models.ArchiveStatusEmpty,
+	models.ArchiveStatusBuilding,
+	models.ArchiveStatusReady,
+	models.ArchiveStatusFailed,
+}
+
+// saveBody атомарно пишет архив и переносит его ID в индексы нового статуса.
+// KEYS[1] — ключ архива, затем индексы статусов по UpdatedAt и такие же по CreatedAt.
+// ARGV: JSON, ID, номер ключа индекса нового статуса (0 — без индекса), score UpdatedAt,
+// TTL в мс (0 — без TTL), score CreatedAt.
+const saveBody = `
+for i = 2, #KEYS do
+	redis.call('ZREM', KEYS[i], ARGV[2])
+end
+local idx = tonumber(ARGV[3])
+if idx > 0 then
+	redis.call('ZADD', KEYS[idx], ARGV[4], ARGV[2])
+	redis.call('ZADD', KEYS[idx + (#KEYS - 1) / 2], ARGV[6], ARGV[2])
+end
+local ttl = tonumber(ARGV[5])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`
+
+var saveScript = redis.NewScript(saveBody)
+
+// insertScript выполняет saveBody, только если задач в работе меньше лимита, иначе возвращает 0.
+// KEYS[2] и KEYS[3] — индексы empty и building. ARGV[7] — лимит, ARGV[8] — минимальный
+// score не просроченной задачи.
+var insertScript = redis.NewScript(`
+local busy = redis.call('ZCOUNT', KEYS[2], ARGV[8], '+inf') + redis.call('ZCOUNT', KEYS[3], ARGV[8], '+inf')
+if busy >= tonumber(ARGV[7]) then
+	return 0
+end
+` + saveBody)
+
+// deleteScript удаляет архив и его ID из всех индексов. Возвращает 0, если архива нет.
+var deleteScript = redis.NewScript(`
+if redis.call('DEL', KEYS[1]) == 0 then
+	return 0
+end
+for i = 2, #KEYS do
+	redis.call('ZREM', KEYS[i], ARGV[1])
+end
+return 1
+`)
+
+// backfillScript добавляет ID в индекс по CreatedAt (KEYS[2]), только если ID все еще в
+// индексе статуса (KEYS[1]): архив мог сменить статус, пока шло дозаполнение.
+// ARGV — пары score, ID.
+var backfillScript = redis.NewScript(`
+for i = 1, #ARGV, 2 do
+	if redis.call('ZSCORE', KEYS[1], ARGV[i + 1]) then
+		redis.call('ZADD', KEYS[2], ARGV[i], ARGV[i + 1])
+	end
+end
+return 1
+`)
+
+// listChunkSize — сколько записей ListArchives читает из индекса и MGET за раз.
+const listChunkSize = 256
+
+// maxUpdateRetries — сколько раз UpdateArchive перечитывает архив, если его изменили параллельно.
+const maxUpdateRetries = 10
+
+// redisDB хранит архивы в Redis, чтобы несколько реплик видели одни и те же задачи.
+// Запись архива — JSON по ключу <prefix>archive:<id>. Для каждого статуса ведется
+// sorted set <prefix>status:<status> со score = UpdatedAt в миллисекундах: по нему
+// считаются задачи в работе на всем кластере и ищутся просроченные. Второй sorted set
+// <prefix>created:<status> со score = CreatedAt нужен для постраничного списка по
+// дате создания. Задачи empty и building живут не дольше ttl с момента обновления
+// за счет TTL ключа.
+//
+// Префикс по умолчанию содержит hash tag, чтобы все ключи попадали в один слот Redis Cluster.
+type redisDB struct {
+	logger *zap.Logger
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+func New(log *zap.Logger, client redis.UniversalClient, prefix string, ttl time.Duration) infra.Database {
+	return &redisDB{
+		logger: log,
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (db *redisDB) Close() error {
+	return db.client.Close()
+}
+
+func (db *redisDB) SaveArchive(ctx context.Context, archive *models.Archive) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if archive == nil {
+		return ErrArchiveNil
+	}
+
+	if archive.ID == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	args, err := db.saveArgs(archive)
+	if err != nil {
+		return err
+	}
+
+	if err := saveScript.Run(ctx, db.client, db.keys(archive.ID), args...).Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+
+	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
+	return nil
+}
+
+func (db *redisDB) InsertArchive(ctx context.Context, archive *models.Archive, maxInProcess int) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if archive == nil {
+		return ErrArchiveNil
+	}
+
+	if archive.ID == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	args, err := db.saveArgs(archive)
+	if err != nil {
+		return err
+	}
+	args = append(args, maxInProcess, db.minInProcessScore(time.Now()))
+
+	inserted, err := insertScript.Run(ctx, db.client, db.keys(archive.ID), args...).Int()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+	if inserted == 0 {
+		return ErrLimitReached
+	}
+
+	db.logger.Info("архив сохранен", zap.String("archive_id", archive.ID))
+	return nil
+}
+
+func (db *redisDB) GetArchive(ctx context.Context, id string) (*models.Archive, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrArchiveIDEmpty
+	}
+
+	data, err := db.client.Get(ctx, db.archiveKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+
+	return decode(data)
+}
+
+func (db *redisDB) UpdateArchive(ctx context.Context, id string, fn func(*models.Archive) error) (*models.Archive, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return nil, ErrArchiveIDEmpty
+	}
+
+	key := db.archiveKey(id)
+	var archive *models.Archive
+
+	// оптимистичная блокировка: если ключ изменился между GET и EXEC, читаем заново
+	update := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrArchiveNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+
+		archive, err = decode(data)
+		if err != nil {
+			return err
+		}
+		if err := fn(archive); err != nil {
+			return err
+		}
+		archive.ID = id
+
+		args, err := db.saveArgs(archive)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			saveScript.Eval(ctx, pipe, db.keys(id), args...)
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.TxFailedErr) {
+			return fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+		return err
+	}
+
+	for range maxUpdateRetries {
+		err := db.client.Watch(ctx, update, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		db.logger.Info("архив обновлен", zap.String("archive_id", id))
+		return archive, nil
+	}
+
+	return nil, ErrConflict
+}
+
+// ListArchives читает индексы нужных статусов в порядке сортировки, начиная с курсора,
+// и останавливается, как только набрана страница. Ключи, удаленные по TTL, пропускаются.
+func (db *redisDB) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	after, err := listing.ParseCursor(query)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := query.Statuses
+	if len(wanted) == 0 {
+		wanted = statuses
+	}
+
+	if query.SortBy != models.SortByUpdatedAt {
+		if err := db.backfillCreated(ctx, wanted); err != nil {
+			return nil, err
+		}
+	}
+
+	// каждый статус дает не больше страницы и одного архива сверх нее: этого хватает,
+	// чтобы listing.Page выбрал общую страницу и понял, есть ли следующая
+	need := 0
+	if query.Limit > 0 {
+		need = query.Limit + 1
+	}
+
+	var archives []*models.Archive
+	for _, status := range wanted {
+		found, err := db.listIndex(ctx, status, query, after, need)
+		if err != nil {
+			return nil, err
+		}
+		archives = append(archives, found...)
+	}
+
+	return listing.Page(archives, query)
+}
+
+// listIndex читает архивы статуса из индекса по полю сортировки порциями по listChunkSize.
+// Score в индексе — миллисекунды, а порядок страницы задают наносекунды и ID, поэтому
+// после need подходящих архивов дочитываются все с тем же score. need = 0 — читать до конца.
+func (db *redisDB) listIndex(ctx context.Context, status models.ArchiveStatus, query models.ArchiveQuery, after *listing.Cursor, need int) ([]*models.Archive, error) {
+	key := db.createdKey(status)
+	if query.SortBy == models.SortByUpdatedAt {
+		key = db.statusKey(status)
+	}
+
+	bound := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: listChunkSize}
+	if query.SortBy != models.SortByUpdatedAt {
+		if !query.CreatedFrom.IsZero() {
+			bound.Min = scoreOf(query.CreatedFrom)
+		}
+		if !query.CreatedTo.IsZero() {
+			bound.Max = scoreOf(query.CreatedTo)
+		}
+	}
+	if after != nil {
+		from := scoreOf(time.Unix(0, after.Key()))
+		if query.Desc {
+			bound.Max = from
+		} else {
+			bound.Min = from
+		}
+	}
+
+	var (
+		found []*models.Archive
+		last  float64
+	)
+	for {
+		var members []redis.Z
+		var err error
+		if query.Desc {
+			members, err = db.client.ZRevRangeByScoreWithScores(ctx, key, bound).Result()
+		} else {
+			members, err = db.client.ZRangeByScoreWithScores(ctx, key, bound).Result()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+		if len(members) == 0 {
+			return found, nil
+		}
+
+		keys := make([]string, len(members))
+		for i, member := range members {
+			keys[i] = db.archiveKey(member.Member.(string))
+		}
+		values, err := db.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+
+		for i, value := range values {
+			if need > 0 && len(found) >= need && members[i].Score != last {
+				return found, nil
+			}
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+			archive, err := decode([]byte(data))
+			if err != nil {
+				return nil, err
+			}
+			if archive.Status != status || !listing.Match(archive, query) || (after != nil && after.Passed(archive)) {
+				continue
+			}
+			found = append(found, archive)
+			last = members[i].Score
+		}
+
+		if len(members) < listChunkSize {
+			return found, nil
+		}
+		bound.Offset += listChunkSize
+	}
+}
+
+// backfillCreated дозаполняет индекс по CreatedAt для архивов, сохраненных до его
+// появления. Оба индекса меняются одними скриптами, поэтому разный размер значит,
+// что индекс по CreatedAt неполон.
+func (db *redisDB) backfillCreated(ctx context.Context, wanted []models.ArchiveStatus) error {
+	sizes := make([][2]*redis.IntCmd, len(wanted))
+	_, err := db.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, status := range wanted {
+			sizes[i] = [2]*redis.IntCmd{pipe.ZCard(ctx, db.statusKey(status)), pipe.ZCard(ctx, db.createdKey(status))}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+
+	for i, status := range wanted {
+		if sizes[i][1].Val() >= sizes[i][0].Val() {
+			continue
+		}
+
+		ids, err := db.client.ZRange(ctx, db.statusKey(status), 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+		for chunk := range slices.Chunk(ids, listChunkSize) {
+			keys := make([]string, len(chunk))
+			for j, id := range chunk {
+				keys[j] = db.archiveKey(id)
+			}
+			values, err := db.client.MGet(ctx, keys...).Result()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrRedis, err)
+			}
+
+			var args []any
+			for j, value := range values {
+				data, ok := value.(string)
+				if !ok {
+					continue
+				}
+				archive, err := decode([]byte(data))
+				if err != nil {
+					return err
+				}
+				args = append(args, archive.CreatedAt.UnixMilli(), chunk[j])
+			}
+			if len(args) == 0 {
+				continue
+			}
+			keys = []string{db.statusKey(status), db.createdKey(status)}
+			if err := backfillScript.Run(ctx, db.client, keys, args...).Err(); err != nil {
+				return fmt.Errorf("%w: %v", ErrRedis, err)
+			}
+		}
+
+		db.logger.Info("индекс архивов по дате создания дозаполнен", zap.String("status", string(status)))
+	}
+
+	return nil
+}
+
+func (db *redisDB) CountArchivesInProcess(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	from := db.minInProcessScore(time.Now())
+
+	var empty, building *redis.IntCmd
+	_, err := db.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		empty = pipe.ZCount(ctx, db.statusKey(models.ArchiveStatusEmpty), from, "+inf")
+		building = pipe.ZCount(ctx, db.statusKey(models.ArchiveStatusBuilding), from, "+inf")
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+
+	return int(empty.Val() + building.Val()), nil
+}
+
+func (db *redisDB) DeleteArchive(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if id == "" {
+		return ErrArchiveIDEmpty
+	}
+
+	deleted, err := deleteScript.Run(ctx, db.client, db.keys(id), id).Int()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+	if deleted == 0 {
+		return ErrArchiveNotFound
+	}
+
+	db.logger.Info("архив удален", zap.String("archive_id", id))
+	return nil
+}
+
+func (db *redisDB) ListExpired(ctx context.Context, retention map[models.ArchiveStatus]time.Duration) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	now := time.Now()
+	var ids []string
+
+	for _, status := range statuses {
+		ttl, ok := retention[status]
+		if !ok || ttl <= 0 {
+			continue
+		}
+
+		// score строго меньше now-ttl: как и в памяти, истекает то, что старше срока
+		to := "(" + scoreOf(now.Add(-ttl))
+		candidates, err := db.client.ZRangeByScore(ctx, db.statusKey(status), &redis.ZRangeBy{Min: "-inf", Max: to}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+
+		// ID с ключами, удаленными по TTL, тоже возвращаются: записи уже нет, но файлы
+		// архива на диске остались, и их должен удалить janitor
+		if err := db.dropMissing(ctx, status, candidates); err != nil {
+			return nil, err
+		}
+		ids = append(ids, candidates...)
+	}
+
+	return ids, nil
+}
+
+// dropMissing убирает из индекса ID, чьи ключи уже удалены Redis по TTL.
+func (db *redisDB) dropMissing(ctx context.Context, status models.ArchiveStatus, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	exists := make([]*redis.IntCmd, len(ids))
+	_, err := db.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			exists[i] = pipe.Exists(ctx, db.archiveKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRedis, err)
+	}
+
+	var missing []any
+	for i, id := range ids {
+		if exists[i].Val() == 0 {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		_, err := db.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRem(ctx, db.statusKey(status), missing...)
+			pipe.ZRem(ctx, db.createdKey(status), missing...)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRedis, err)
+		}
+	}
+
+	return nil
+}
+
+// saveArgs собирает ARGV для saveScript.
+func (db *redisDB) saveArgs(archive *models.Archive) ([]any, error) {
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEncode, err)
+	}
+
+	index := 0
+	for i, status := range statuses {
+		if status == archive.Status {
+			index = i + 2
+		}
+	}
+
+	var ttl int64
+	if inProcess(archive.Status) && db.ttl > 0 {
+		ttl = max((db.ttl - time.Since(archive.UpdatedAt)).Milliseconds(), 1)
+	}
+
+	return []any{data, archive.ID, index, archive.UpdatedAt.UnixMilli(), ttl, archive.CreatedAt.UnixMilli()}, nil
+}
+
+// minInProcessScore — наименьший UpdatedAt (score), при котором задача еще не просрочена.
+func (db *redisDB) minInProcessScore(now time.Time) string {
+	return scoreOf(now.Add(-db.ttl))
+}
+
+// scoreOf — score индекса для момента времени: миллисекунды Unix.
+func scoreOf(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func (db *redisDB) archiveKey(id string) string {
+	return db.prefix + "archive:" + id
+}
+
+func (db *redisDB) statusKey(status models.ArchiveStatus) string {
+	return db.prefix + "status:" + string(status)
+}
+
+func (db *redisDB) createdKey(status models.ArchiveStatus) string {
+	return db.prefix + "created:" + string(status)
+}
+
+// keys — ключ архива, индексы всех статусов по UpdatedAt и по CreatedAt, в порядке statuses.
+func (db *redisDB) keys(id string) []string {
+	keys := make([]string, 0, 2*len(statuses)+1)
+	keys = append(keys, db.archiveKey(id))
+	for _, status := range statuses {
+		keys = append(keys, db.statusKey(status))
+	}
+	for _, status := range statuses {
+		keys = append(keys, db.createdKey(status))
+	}
+	return keys
+}
+
+func inProcess(status models.ArchiveStatus) bool {
+	return status == models.ArchiveStatusEmpty || status == models.ArchiveStatusBuilding
+}
+
+func decode(data []byte) (*models.Archive, error) {
+	var archive models.Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return &archive, nil
+}
@@ -0,0 +1,199 @@
+package redisdb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/sunr3d/05-08-2025/internal/infra/dbtest"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+const testPrefix = "{test}:"
+
+func newTestDB(t *testing.T, ttl time.Duration) (infra.Database, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return New(zaptest.NewLogger(t), client, testPrefix, ttl), mr
+}
+
+func TestRedisDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, ttl time.Duration) infra.Database {
+		db, _ := newTestDB(t, ttl)
+		return db
+	})
+}
+
+func TestRedisDB_InProcessKeysExpire(t *testing.T) {
+	db, mr := newTestDB(t, time.Hour)
+	ctx := context.Background()
+
+	now := time.Now()
+	require.NoError(t, db.SaveArchive(ctx, &models.Archive{ID: "b", Status: models.ArchiveStatusBuilding, UpdatedAt: now}))
+	require.NoError(t, db.SaveArchive(ctx, &models.Archive{ID: "r", Status: models.ArchiveStatusReady, UpdatedAt: now}))
+
+	assert.InDelta(t, time.Hour, mr.TTL(testPrefix+"archive:b"), float64(time.Second))
+	assert.Zero(t, mr.TTL(testPrefix+"archive:r"))
+
+	mr.FastForward(2 * time.Hour)
+
+	_, err := db.GetArchive(ctx, "b")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+	_, err = db.GetArchive(ctx, "r")
+	assert.NoError(t, err)
+}
+
+func TestRedisDB_ListExpiredReturnsExpiredKeys(t *testing.T) {
+	db, mr := newTestDB(t, time.Hour)
+	ctx := context.Background()
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, db.SaveArchive(ctx, &models.Archive{ID: "b", Status: models.ArchiveStatusBuilding, UpdatedAt: old}))
+	mr.FastForward(time.Second)
+	require.False(t, mr.Exists(testPrefix+"archive:b"))
+
+	retention := map[models.ArchiveStatus]time.Duration{models.ArchiveStatusBuilding: time.Hour}
+
+	// записи уже нет, но ID отдается, чтобы janitor удалил файлы архива
+	ids, err := db.ListExpired(ctx, retention)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+
+	ids, err = db.ListExpired(ctx, retention)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestRedisDB_StatusChangeMovesIndex(t *testing.T) {
+	db, mr := newTestDB(t, time.Hour)
+	ctx := context.Background()
+
+	archive := &models.Archive{ID: "a", Status: models.ArchiveStatusBuilding, UpdatedAt: time.Now()}
+	require.NoError(t, db.SaveArchive(ctx, archive))
+
+	count, err := db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	archive.Status = models.ArchiveStatusReady
+	require.NoError(t, db.SaveArchive(ctx, archive))
+
+	count, err = db.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Zero(t, mr.TTL(testPrefix+"archive:a"))
+
+	members, err := mr.ZMembers(testPrefix + "status:ready")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, members)
+	assert.False(t, mr.Exists(testPrefix+"status:building"))
+}
+
+func TestRedisDB_SharedBetweenReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	newReplica := func() infra.Database {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return New(logger, client, testPrefix, time.Hour)
+	}
+	first, second := newReplica(), newReplica()
+
+	require.NoError(t, first.SaveArchive(ctx, &models.Archive{ID: "a", Status: models.ArchiveStatusEmpty, UpdatedAt: time.Now()}))
+
+	got, err := second.GetArchive(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusEmpty, got.Status)
+
+	count, err := second.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// Страница больше порции чтения из индекса: обход должен пройти границы порций без
+// потерь и повторов в обоих индексах и обоих направлениях, даже когда у архивов один
+// score в индексе, а точное время различается.
+func TestRedisDB_ListArchivesPagesAcrossChunks(t *testing.T) {
+	db, _ := newTestDB(t, time.Hour)
+	ctx := context.Background()
+
+	const n = 2*listChunkSize + 10
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond)
+	for i := range n {
+		require.NoError(t, db.SaveArchive(ctx, &models.Archive{
+			ID:        fmt.Sprintf("a%04d", i),
+			Status:    models.ArchiveStatusReady,
+			// внутри одной миллисекунды порядок по времени обратен порядку ID
+			CreatedAt: base.Add(time.Duration(i/4)*time.Millisecond + time.Duration(3-i%4)*time.Microsecond),
+			UpdatedAt: base.Add(time.Duration(n-i/4)*time.Millisecond + time.Duration(i%4)*time.Microsecond),
+		}))
+	}
+
+	for _, sortBy := range []models.ArchiveSortField{models.SortByCreatedAt, models.SortByUpdatedAt} {
+		for _, desc := range []bool{false, true} {
+			query := models.ArchiveQuery{SortBy: sortBy, Desc: desc, Limit: listChunkSize + 5}
+			var got []string
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, 10, "курсор не продвигается")
+
+				page, err := db.ListArchives(ctx, query)
+				require.NoError(t, err)
+				for _, archive := range page.Archives {
+					got = append(got, archive.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			all, err := db.ListArchives(ctx, models.ArchiveQuery{SortBy: sortBy, Desc: desc})
+			require.NoError(t, err)
+			want := make([]string, len(all.Archives))
+			for i, archive := range all.Archives {
+				want[i] = archive.ID
+			}
+			assert.Len(t, got, n)
+			assert.Equal(t, want, got, "sort=%s desc=%v", sortBy, desc)
+		}
+	}
+}
+
+// Архивы, сохраненные до появления индекса по CreatedAt, попадают в него при первом списке.
+func TestRedisDB_ListArchivesBackfillsCreatedIndex(t *testing.T) {
+	db, mr := newTestDB(t, time.Hour)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Minute).UTC().Truncate(time.Millisecond)
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, db.SaveArchive(ctx, &models.Archive{
+			ID: id, Status: models.ArchiveStatusReady,
+			CreatedAt: base.Add(time.Duration(i) * time.Second), UpdatedAt: base,
+		}))
+	}
+	mr.Del(testPrefix + "created:ready")
+
+	page, err := db.ListArchives(ctx, models.ArchiveQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Archives, 2)
+	assert.Equal(t, "a", page.Archives[0].ID)
+	assert.Equal(t, "b", page.Archives[1].ID)
+
+	members, err := mr.ZMembers(testPrefix + "created:ready")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, members)
+}
@@ -2,6 +2,7 @@ package infra
 
 import (
 	"context"
+	"time"
 
 	"github.com/sunr3d/05-08-2025/models"
 )
@@ -9,7 +10,23 @@ import (
 //go:generate go run github.com/vektra/mockery/v2@v2.53.2 --name=Database --output=../../../mocks
 type Database interface {
 	SaveArchive(ctx context.Context, archive *models.Archive) error
+	// InsertArchive сохраняет новый архив, только если задач в работе (empty/building,
+	// не просроченных по TTL) меньше maxInProcess, иначе возвращает ErrLimitReached.
+	// Подсчет и запись выполняются атомарно.
+	InsertArchive(ctx context.Context, archive *models.Archive, maxInProcess int) error
 	GetArchive(ctx context.Context, id string) (*models.Archive, error)
+	// ListArchives возвращает страницу архивов по фильтру. Некорректный курсор — ErrInvalidCursor.
+	ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error)
 	CountArchivesInProcess(ctx context.Context) (int, error)
+	// UpdateArchive атомарно читает архив, применяет к нему fn и сохраняет результат.
+	// Если fn вернула ошибку, запись не меняется, а ошибка возвращается как есть.
+	// fn может быть вызвана повторно при конфликте конкурентных изменений, поэтому
+	// должна только менять переданную копию.
+	UpdateArchive(ctx context.Context, id string, fn func(*models.Archive) error) (*models.Archive, error)
 	DeleteArchive(ctx context.Context, id string) error
+	// ListExpired возвращает ID архивов, которые не обновлялись дольше срока хранения
+	// для своего статуса. Статусы без срока (или с нулевым сроком) не истекают.
+	// Среди них могут быть ID архивов, чьи записи хранилище уже удалило само
+	// (Redis по TTL): файлы таких архивов на диске все равно нужно удалить.
+	ListExpired(ctx context.Context, retention map[models.ArchiveStatus]time.Duration) ([]string, error)
 }
@@ -0,0 +1,14 @@
+package infra
+
+import "errors"
+
+// Общие ошибки хранилища: все реализации Database возвращают именно их,
+// чтобы вызывающий код не зависел от выбранного драйвера.
+var (
+	ErrArchiveNotFound = errors.New("архив не найден")
+	ErrArchiveNil      = errors.New("архив не может быть nil")
+	ErrArchiveIDEmpty  = errors.New("ID архива не может быть пустым")
+	ErrContextDone     = errors.New("отмена контекста")
+	ErrLimitReached    = errors.New("достигнут лимит задач в работе")
+	ErrInvalidCursor   = errors.New("некорректный курсор")
+)
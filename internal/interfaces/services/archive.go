@@ -12,5 +12,11 @@ type ArchiveService interface {
 
 	CreateEmptyArchive(ctx context.Context) (*models.Archive, error)
 	AddFile(ctx context.Context, archiveID, fileURL string) error
+	RemoveFile(ctx context.Context, archiveID, filename string) error
 	GetArchive(ctx context.Context, archiveID string) (*models.Archive, error)
+	ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error)
+	Finalize(ctx context.Context, archiveID string) (*models.Archive, error)
+	DeleteArchive(ctx context.Context, archiveID string) error
+
+	Shutdown(ctx context.Context) error
 }
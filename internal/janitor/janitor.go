@@ -0,0 +1,185 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/services"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// Janitor периодически удаляет архивы, срок хранения которых для их статуса истек,
+// вместе с готовыми zip и временными директориями.
+type Janitor struct {
+	repo      infra.Database
+	svc       services.ArchiveService
+	logger    *zap.Logger
+	cfg       *config.Config
+	retention map[models.ArchiveStatus]time.Duration
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Report — итог одного прохода.
+type Report struct {
+	Archives int
+	Bytes    int64
+	Failed   int
+}
+
+func New(log *zap.Logger, cfg *config.Config, repo infra.Database, svc services.ArchiveService) *Janitor {
+	return &Janitor{
+		repo:   repo,
+		svc:    svc,
+		logger: log,
+		cfg:    cfg,
+		retention: map[models.ArchiveStatus]time.Duration{
+			models.ArchiveStatusEmpty:    cfg.RetentionEmpty,
+			models.ArchiveStatusBuilding: cfg.RetentionBuilding,
+			models.ArchiveStatusReady:    cfg.RetentionReady,
+			models.ArchiveStatusFailed:   cfg.RetentionFailed,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start запускает периодическую очистку. Нулевой JANITOR_INTERVAL отключает ее.
+func (j *Janitor) Start() {
+	if j.cfg.JanitorInterval <= 0 {
+		j.logger.Info("janitor отключен")
+		close(j.done)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-j.stop
+		cancel()
+	}()
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.cfg.JanitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.Sweep(ctx)
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	j.logger.Info("janitor запущен", zap.Duration("interval", j.cfg.JanitorInterval))
+}
+
+// Sweep удаляет все просроченные архивы и возвращает, сколько места освобождено.
+func (j *Janitor) Sweep(ctx context.Context) Report {
+	var report Report
+
+	ids, err := j.repo.ListExpired(ctx, j.retention)
+	if err != nil {
+		j.logger.Error("janitor: не удалось получить просроченные архивы", zap.Error(err))
+		return report
+	}
+
+	for _, id := range ids {
+		size := pathSize(filepath.Join(j.cfg.TempDir, id)) + pathSize(filepath.Join(j.cfg.ArchivesDir, id+".zip"))
+
+		err := j.svc.DeleteArchive(ctx, id)
+		if errors.Is(err, infra.ErrArchiveNotFound) {
+			// записи уже нет: ее удалили параллельно или хранилище удалило ее по TTL,
+			// но файлы архива на диске могли остаться
+			var removed bool
+			if removed, err = j.removeFiles(id); err == nil && !removed {
+				continue
+			}
+		}
+		if err != nil {
+			report.Failed++
+			j.logger.Error("janitor: не удалось удалить архив",
+				zap.String("archive_id", id),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		report.Archives++
+		report.Bytes += size
+	}
+
+	if report.Archives > 0 || report.Failed > 0 {
+		j.logger.Info("janitor: просроченные архивы удалены",
+			zap.Int("archives", report.Archives),
+			zap.Int64("bytes", report.Bytes),
+			zap.Int("failed", report.Failed),
+		)
+	}
+
+	return report
+}
+
+// Shutdown останавливает очистку и ждет завершения текущего прохода.
+func (j *Janitor) Shutdown(ctx context.Context) error {
+	j.stopOnce.Do(func() { close(j.stop) })
+
+	select {
+	case <-j.done:
+		j.logger.Info("janitor остановлен")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("janitor не остановился: %w", ctx.Err())
+	}
+}
+
+// removeFiles удаляет временную директорию и zip архива без записи в хранилище.
+// Возвращает false, если удалять было нечего.
+func (j *Janitor) removeFiles(id string) (bool, error) {
+	removed := false
+	for _, path := range []string{filepath.Join(j.cfg.TempDir, id), filepath.Join(j.cfg.ArchivesDir, id+".zip")} {
+		if _, err := os.Lstat(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, err
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return removed, err
+		}
+		removed = true
+	}
+	return removed, nil
+}
+
+// pathSize возвращает размер файла или суммарный размер файлов директории.
+func pathSize(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
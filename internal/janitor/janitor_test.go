@@ -0,0 +1,322 @@
+package janitor
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/infra/inmem"
+	"github.com/sunr3d/05-08-2025/internal/infra/redisdb"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/services/archive_service"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+func setupTestJanitor(t *testing.T) (*Janitor, infra.Database, *config.Config) {
+	t.Helper()
+
+	logger := zaptest.NewLogger(t)
+	dir := t.TempDir()
+
+	cfg := &config.Config{
+		MaxArchivesInProcess: 3,
+		MaxFilesPerArchive:   3,
+		ArchiveTTL:           time.Hour,
+		ArchivesDir:          filepath.Join(dir, "archives"),
+		TempDir:              filepath.Join(dir, "temp"),
+		WorkersCount:         1,
+		WorkerQueueSize:      1,
+		JanitorInterval:      10 * time.Millisecond,
+		RetentionEmpty:       time.Hour,
+		RetentionBuilding:    time.Hour,
+		RetentionReady:       time.Hour,
+		RetentionFailed:      time.Minute,
+	}
+	require.NoError(t, os.MkdirAll(cfg.ArchivesDir, 0755))
+	require.NoError(t, os.MkdirAll(cfg.TempDir, 0755))
+
+	repo := inmem.New(logger, cfg.ArchiveTTL)
+	svc := archive_service.New(logger, cfg, repo)
+	t.Cleanup(func() { svc.Shutdown(context.Background()) })
+
+	return New(logger, cfg, repo, svc), repo, cfg
+}
+
+func saveArchive(t *testing.T, repo infra.Database, cfg *config.Config, id string, status models.ArchiveStatus, age time.Duration) {
+	t.Helper()
+
+	require.NoError(t, repo.SaveArchive(context.Background(), &models.Archive{
+		ID:        id,
+		Status:    status,
+		Files:     []string{"file.pdf"},
+		CreatedAt: time.Now().Add(-age),
+		UpdatedAt: time.Now().Add(-age),
+	}))
+
+	tempDir := filepath.Join(cfg.TempDir, id)
+	require.NoError(t, os.MkdirAll(tempDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "file.pdf"), make([]byte, 100), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.ArchivesDir, id+".zip"), make([]byte, 50), 0644))
+}
+
+func TestJanitor_Sweep(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	ctx := context.Background()
+
+	saveArchive(t, repo, cfg, "ready-old", models.ArchiveStatusReady, 2*time.Hour)
+	saveArchive(t, repo, cfg, "ready-fresh", models.ArchiveStatusReady, time.Minute)
+	saveArchive(t, repo, cfg, "failed-old", models.ArchiveStatusFailed, 2*time.Minute)
+
+	report := j.Sweep(ctx)
+	assert.Equal(t, Report{Archives: 2, Bytes: 300}, report)
+
+	for _, id := range []string{"ready-old", "failed-old"} {
+		_, err := repo.GetArchive(ctx, id)
+		assert.ErrorIs(t, err, inmem.ErrArchiveNotFound, id)
+		assert.NoDirExists(t, filepath.Join(cfg.TempDir, id))
+		assert.NoFileExists(t, filepath.Join(cfg.ArchivesDir, id+".zip"))
+	}
+
+	_, err := repo.GetArchive(ctx, "ready-fresh")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(cfg.ArchivesDir, "ready-fresh.zip"))
+}
+
+func TestJanitor_Sweep_RedisExpiredKeys(t *testing.T) {
+	j, _, cfg := setupTestJanitor(t)
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	repo := redisdb.New(zaptest.NewLogger(t), client, "{test}:", cfg.ArchiveTTL)
+	svc := archive_service.New(zaptest.NewLogger(t), cfg, repo)
+	t.Cleanup(func() { svc.Shutdown(context.Background()) })
+	j.repo, j.svc = repo, svc
+
+	saveArchive(t, repo, cfg, "building-old", models.ArchiveStatusBuilding, 2*time.Hour)
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.TempDir, "building-old", "next.pdf"+partSuffix), make([]byte, 10), 0644))
+
+	// Redis удаляет ключ задачи в работе по ARCHIVE_TTL раньше, чем до нее доходит janitor
+	mr.FastForward(time.Second)
+	_, err := repo.GetArchive(ctx, "building-old")
+	require.ErrorIs(t, err, infra.ErrArchiveNotFound)
+
+	report := j.Sweep(ctx)
+	assert.Equal(t, Report{Archives: 1, Bytes: 160}, report)
+	assert.NoDirExists(t, filepath.Join(cfg.TempDir, "building-old"))
+	assert.NoFileExists(t, filepath.Join(cfg.ArchivesDir, "building-old.zip"))
+
+	assert.Equal(t, Report{}, j.Sweep(ctx))
+}
+
+func TestJanitor_ZeroRetentionKeepsForever(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	j.retention[models.ArchiveStatusReady] = 0
+
+	saveArchive(t, repo, cfg, "ready-old", models.ArchiveStatusReady, 24*time.Hour)
+
+	report := j.Sweep(context.Background())
+	assert.Zero(t, report.Archives)
+	assert.FileExists(t, filepath.Join(cfg.ArchivesDir, "ready-old.zip"))
+}
+
+func TestJanitor_StartAndShutdown(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+
+	saveArchive(t, repo, cfg, "failed-old", models.ArchiveStatusFailed, time.Hour)
+
+	j.Start()
+
+	assert.Eventually(t, func() bool {
+		_, err := repo.GetArchive(context.Background(), "failed-old")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, j.Shutdown(ctx))
+	require.NoError(t, j.Shutdown(ctx))
+}
+
+func TestJanitor_Disabled(t *testing.T) {
+	j, _, cfg := setupTestJanitor(t)
+	cfg.JanitorInterval = 0
+
+	j.Start()
+	assert.NoError(t, j.Shutdown(context.Background()))
+}
+
+func writeZip(t *testing.T, path string, names ...string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		w.Write([]byte("data"))
+	}
+	require.NoError(t, zw.Close())
+}
+
+func TestJanitor_Reconcile_Adopt(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	cfg.OrphanZipPolicy = config.OrphanZipAdopt
+	ctx := context.Background()
+
+	orphanID, aliveID, lostID, brokenID := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+
+	// временная директория без записи — остаток прерванной сборки
+	orphanTemp := filepath.Join(cfg.TempDir, orphanID)
+	require.NoError(t, os.MkdirAll(orphanTemp, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(orphanTemp, "a.pdf.part"), make([]byte, 10), 0644))
+
+	// у живого архива остается добавленный файл, но не .part
+	require.NoError(t, repo.SaveArchive(ctx, &models.Archive{
+		ID: aliveID, Status: models.ArchiveStatusBuilding, Files: []string{"a.pdf"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	aliveTemp := filepath.Join(cfg.TempDir, aliveID)
+	require.NoError(t, os.MkdirAll(aliveTemp, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(aliveTemp, "a.pdf"), make([]byte, 10), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(aliveTemp, "b.pdf.part"), make([]byte, 5), 0644))
+
+	writeZip(t, filepath.Join(cfg.ArchivesDir, lostID+".zip"), "x.pdf", "y.jpg")
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.ArchivesDir, brokenID+".zip"), []byte("not a zip"), 0644))
+
+	// чужие файлы в общих директориях сверка не трогает
+	foreignTemp := filepath.Join(cfg.TempDir, "cache")
+	require.NoError(t, os.MkdirAll(foreignTemp, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(foreignTemp, "x.part"), make([]byte, 5), 0644))
+	writeZip(t, filepath.Join(cfg.ArchivesDir, "backup.zip"), "db.sql")
+
+	report, err := j.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.TempDirsRemoved)
+	assert.Equal(t, 1, report.PartsRemoved)
+	assert.Equal(t, 1, report.ZipsAdopted)
+	assert.Equal(t, 1, report.ZipsRemoved)
+
+	assert.NoDirExists(t, orphanTemp)
+	assert.FileExists(t, filepath.Join(aliveTemp, "a.pdf"))
+	assert.NoFileExists(t, filepath.Join(aliveTemp, "b.pdf.part"))
+	assert.NoFileExists(t, filepath.Join(cfg.ArchivesDir, brokenID+".zip"))
+	assert.FileExists(t, filepath.Join(foreignTemp, "x.part"))
+	assert.FileExists(t, filepath.Join(cfg.ArchivesDir, "backup.zip"))
+
+	adopted, err := repo.GetArchive(ctx, lostID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, adopted.Status)
+	assert.Equal(t, []string{"x.pdf", "y.jpg"}, adopted.Files)
+
+	_, err = repo.GetArchive(ctx, "backup")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+func TestJanitor_Reconcile_Delete(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	cfg.OrphanZipPolicy = config.OrphanZipDelete
+	ctx := context.Background()
+
+	lostID := uuid.NewString()
+	writeZip(t, filepath.Join(cfg.ArchivesDir, lostID+".zip"), "x.pdf")
+
+	report, err := j.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.ZipsRemoved)
+	assert.NoFileExists(t, filepath.Join(cfg.ArchivesDir, lostID+".zip"))
+
+	_, err = repo.GetArchive(ctx, lostID)
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+func TestJanitor_Reconcile_FailsInterruptedJobs(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	ctx := context.Background()
+	jobID := uuid.NewString()
+
+	// задача POST /archive, чья очередь сборки потеряна при рестарте
+	require.NoError(t, repo.SaveArchive(ctx, &models.Archive{
+		ID: jobID, Status: models.ArchiveStatusBuilding, Job: true,
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		Entries: []models.FileEntry{
+			{URL: "https://example.com/a.pdf", Status: models.FileStatusOK, Name: "a.pdf"},
+			{URL: "https://example.com/b.pdf", Status: models.FileStatusDownloading},
+			{URL: "https://example.com/c.pdf", Status: models.FileStatusPending},
+		},
+	}))
+	jobTemp := filepath.Join(cfg.TempDir, jobID)
+	require.NoError(t, os.MkdirAll(jobTemp, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(jobTemp, "a.pdf"), make([]byte, 10), 0644))
+
+	// архив из add-file ждет следующих файлов и остается в работе
+	require.NoError(t, repo.SaveArchive(ctx, &models.Archive{
+		ID: "manual", Status: models.ArchiveStatusBuilding, Files: []string{"a.pdf"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	report, err := j.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.JobsFailed)
+	assert.Equal(t, 1, report.TempDirsRemoved)
+	assert.NoDirExists(t, jobTemp)
+
+	job, err := repo.GetArchive(ctx, jobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusFailed, job.Status)
+	assert.Equal(t, []string{errJobInterrupted.Error()}, job.Errors)
+	assert.Equal(t, models.FileStatusOK, job.Entries[0].Status)
+	for _, entry := range job.Entries[1:] {
+		assert.Equal(t, models.FileStatusFailed, entry.Status)
+		assert.Equal(t, models.CodeServiceStopped, entry.ErrorCode)
+	}
+
+	manual, err := repo.GetArchive(ctx, "manual")
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, manual.Status)
+
+	count, err := repo.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestJanitor_Reconcile_KeepsJobsInRedis(t *testing.T) {
+	j, repo, cfg := setupTestJanitor(t)
+	cfg.StorageDriver = config.StorageRedis
+	ctx := context.Background()
+	jobID := uuid.NewString()
+
+	require.NoError(t, repo.SaveArchive(ctx, &models.Archive{
+		ID: jobID, Status: models.ArchiveStatusBuilding, Job: true,
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	// файл может дописывать другая реплика с общим TEMP_DIR
+	part := filepath.Join(cfg.TempDir, jobID, "a.pdf"+partSuffix)
+	require.NoError(t, os.MkdirAll(filepath.Dir(part), 0755))
+	require.NoError(t, os.WriteFile(part, make([]byte, 5), 0644))
+
+	report, err := j.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.JobsFailed)
+	assert.Zero(t, report.PartsRemoved)
+	assert.FileExists(t, part)
+
+	job, err := repo.GetArchive(ctx, jobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, job.Status)
+}
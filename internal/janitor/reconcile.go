@@ -0,0 +1,278 @@
+package janitor
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+const partSuffix = ".part"
+
+var errJobInterrupted = errors.New("сборка прервана перезапуском сервиса")
+
+// ReconcileReport — итог сверки файлов на диске с хранилищем при старте.
+type ReconcileReport struct {
+	JobsFailed      int
+	TempDirsRemoved int
+	PartsRemoved    int
+	ZipsAdopted     int
+	ZipsRemoved     int
+	Bytes           int64
+}
+
+// Reconcile приводит TEMP_DIR и ARCHIVES_DIR в соответствие с хранилищем. Вызывается до
+// старта HTTP сервера, когда никакой работы по архивам еще не идет:
+//   - архивы задач POST /archive, оставшиеся в building, переводятся в failed:
+//     очередь сборки живет в памяти процесса, и доделать их некому;
+//   - временные директории архивов, которых нет или которые уже собраны, удаляются;
+//   - у архивов empty/building удаляются недокачанные .part файлы (кроме Redis: их
+//     может дописывать другая реплика);
+//   - файлы и директории, чье имя не ID архива, не трогаются;
+//   - zip без записи принимаются как ready или удаляются по ORPHAN_ZIP_POLICY,
+//     поврежденные zip удаляются всегда.
+func (j *Janitor) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	if err := j.failInterruptedJobs(ctx, &report); err != nil {
+		return report, err
+	}
+	if err := j.reconcileTemp(ctx, &report); err != nil {
+		return report, err
+	}
+	if err := j.reconcileZips(ctx, &report); err != nil {
+		return report, err
+	}
+
+	j.logger.Info("сверка файлов при старте завершена",
+		zap.Int("jobs_failed", report.JobsFailed),
+		zap.Int("temp_dirs_removed", report.TempDirsRemoved),
+		zap.Int("parts_removed", report.PartsRemoved),
+		zap.Int("zips_adopted", report.ZipsAdopted),
+		zap.Int("zips_removed", report.ZipsRemoved),
+		zap.Int64("bytes", report.Bytes),
+		zap.String("orphan_zip_policy", j.cfg.OrphanZipPolicy),
+	)
+	return report, nil
+}
+
+// failInterruptedJobs работает только с хранилищами одного процесса: в Redis задача в
+// building может принадлежать другой, работающей реплике.
+func (j *Janitor) failInterruptedJobs(ctx context.Context, report *ReconcileReport) error {
+	if j.cfg.StorageDriver == config.StorageRedis {
+		return nil
+	}
+
+	page, err := j.repo.ListArchives(ctx, models.ArchiveQuery{
+		Statuses: []models.ArchiveStatus{models.ArchiveStatusBuilding},
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось получить архивы в сборке: %w", err)
+	}
+
+	for _, archive := range page.Archives {
+		if !archive.Job {
+			continue
+		}
+
+		_, err := j.repo.UpdateArchive(ctx, archive.ID, failInterrupted)
+		if errors.Is(err, infra.ErrArchiveNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("не удалось отметить прерванную сборку архива %s: %w", archive.ID, err)
+		}
+		report.JobsFailed++
+		j.logger.Warn("сборка архива прервана перезапуском", zap.String("archive_id", archive.ID))
+	}
+
+	return nil
+}
+
+func failInterrupted(a *models.Archive) error {
+	now := time.Now()
+	for i, entry := range a.Entries {
+		if entry.Status != models.FileStatusPending && entry.Status != models.FileStatusDownloading {
+			continue
+		}
+		a.Entries[i].Status = models.FileStatusFailed
+		a.Entries[i].ErrorCode = models.CodeServiceStopped
+		a.Entries[i].Error = errJobInterrupted.Error()
+		a.Entries[i].FinishedAt = now
+	}
+	a.Status = models.ArchiveStatusFailed
+	a.Errors = append(a.Errors, errJobInterrupted.Error())
+	a.UpdatedAt = now
+	return nil
+}
+
+func (j *Janitor) reconcileTemp(ctx context.Context, report *ReconcileReport) error {
+	entries, err := os.ReadDir(j.cfg.TempDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("не удалось прочитать директорию временных файлов: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		dir := filepath.Join(j.cfg.TempDir, id)
+		if !isArchiveID(id) {
+			j.logger.Info("в директории временных файлов пропущена чужая директория", zap.String("path", dir))
+			continue
+		}
+
+		archive, err := j.lookup(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if archive == nil || archive.Status == models.ArchiveStatusReady || archive.Status == models.ArchiveStatusFailed {
+			size := pathSize(dir)
+			if err := os.RemoveAll(dir); err != nil {
+				j.logger.Error("не удалось удалить временную директорию", zap.String("path", dir), zap.Error(err))
+				continue
+			}
+			report.TempDirsRemoved++
+			report.Bytes += size
+			continue
+		}
+
+		// в Redis архив может скачивать другая реплика с общим TEMP_DIR
+		if j.cfg.StorageDriver == config.StorageRedis {
+			continue
+		}
+
+		parts, _ := filepath.Glob(filepath.Join(dir, "*"+partSuffix))
+		for _, part := range parts {
+			size := pathSize(part)
+			if err := os.Remove(part); err != nil {
+				j.logger.Error("не удалось удалить недокачанный файл", zap.String("path", part), zap.Error(err))
+				continue
+			}
+			report.PartsRemoved++
+			report.Bytes += size
+		}
+	}
+
+	return nil
+}
+
+func (j *Janitor) reconcileZips(ctx context.Context, report *ReconcileReport) error {
+	entries, err := os.ReadDir(j.cfg.ArchivesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("не удалось прочитать директорию архивов: %w", err)
+	}
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".zip")
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(j.cfg.ArchivesDir, entry.Name())
+		if !isArchiveID(id) {
+			j.logger.Info("в директории архивов пропущен чужой zip", zap.String("path", path))
+			continue
+		}
+
+		archive, err := j.lookup(ctx, id)
+		if err != nil {
+			return err
+		}
+		if archive != nil {
+			continue
+		}
+
+		files, zipErr := zipEntries(path)
+		if j.cfg.OrphanZipPolicy == config.OrphanZipAdopt && zipErr == nil {
+			if err := j.adopt(ctx, id, path, files); err != nil {
+				return err
+			}
+			report.ZipsAdopted++
+			continue
+		}
+
+		size := pathSize(path)
+		if err := os.Remove(path); err != nil {
+			j.logger.Error("не удалось удалить zip без записи", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if zipErr != nil {
+			j.logger.Warn("удален поврежденный zip", zap.String("path", path), zap.Error(zipErr))
+		}
+		report.ZipsRemoved++
+		report.Bytes += size
+	}
+
+	return nil
+}
+
+func (j *Janitor) adopt(ctx context.Context, id, path string, files []string) error {
+	updatedAt := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		updatedAt = info.ModTime()
+	}
+
+	archive := &models.Archive{
+		ID:        id,
+		Status:    models.ArchiveStatusReady,
+		Files:     files,
+		CreatedAt: updatedAt,
+		UpdatedAt: updatedAt,
+	}
+	if err := j.repo.SaveArchive(ctx, archive); err != nil {
+		return fmt.Errorf("не удалось сохранить найденный архив %s: %w", id, err)
+	}
+	return nil
+}
+
+// lookup возвращает nil без ошибки, если архива нет в хранилище.
+func (j *Janitor) lookup(ctx context.Context, id string) (*models.Archive, error) {
+	archive, err := j.repo.GetArchive(ctx, id)
+	if errors.Is(err, infra.ErrArchiveNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить архив %s: %w", id, err)
+	}
+	return archive, nil
+}
+
+// isArchiveID проверяет, что имя — ID архива в том виде, в каком его создает сервис.
+// Остальные файлы в TEMP_DIR и ARCHIVES_DIR сверка не трогает.
+func isArchiveID(name string) bool {
+	id, err := uuid.Parse(name)
+	return err == nil && id.String() == name
+}
+
+func zipEntries(path string) ([]string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make([]string, 0, len(r.File))
+	for _, f := range r.File {
+		files = append(files, f.Name)
+	}
+	return files, nil
+}
@@ -1,11 +1,18 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/url"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/i18n"
+	"github.com/sunr3d/05-08-2025/models"
 )
 
 func ReqLogger(log *zap.Logger) func(http.Handler) http.Handler {
@@ -23,11 +30,11 @@ func ReqLogger(log *zap.Logger) func(http.Handler) http.Handler {
 func JSONValidator() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == http.MethodPost && requiresJSON(r.URL.Path) {
+			if r.Method == http.MethodPost && requiresJSON(r) {
 				ct := r.Header.Get("Content-Type")
 				base := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
 				if base != "application/json" {
-					http.Error(w, "Неверный Content-Type, ожидается application/json", http.StatusUnsupportedMediaType)
+					writeJSONError(w, r, http.StatusUnsupportedMediaType, models.CodeUnsupportedMediaType, "Неверный Content-Type, ожидается application/json")
 					return
 				}
 			}
@@ -36,13 +43,51 @@ func JSONValidator() func(http.Handler) http.Handler {
 	}
 }
 
-func requiresJSON(path string) bool {
+func requiresJSON(r *http.Request) bool {
 	endpoints := map[string]bool{
-		"/archive":          true,
-		"/archive/add-file": true,
+		"/archive":             true,
+		"/archive/add-file":    true,
+		"/archive/remove-file": true,
 	}
 
-	return endpoints[path]
+	path := r.URL.Path
+	switch {
+	case endpoints[path]:
+		return true
+	case path == "/v1/archives":
+		// без тела создается пустая задача
+		return r.ContentLength != 0
+	case strings.HasPrefix(path, "/v1/archives/") && strings.HasSuffix(path, "/files"):
+		return true
+	}
+	return false
+}
+
+// Deprecated помечает ответы старого роута заголовками Deprecation и Link на замену.
+// Deprecation пишется по RFC 9745: дата устаревания в формате "@<unix-время>".
+// В successor "{id}" заменяется на archive_id из запроса; "{filename}" приходит в теле
+// запроса, поэтому остается шаблоном.
+func Deprecated(since time.Time, successor string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			link := strings.ReplaceAll(successor, "{id}", url.PathEscape(r.URL.Query().Get("archive_id")))
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Link", "<"+link+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Localize выбирает язык ответа по Accept-Language и кладет его в контекст запроса.
+func Localize(def i18n.Lang) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := i18n.Negotiate(r.Header.Get("Accept-Language"), def)
+			w.Header().Add("Vary", "Accept-Language")
+			next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+		})
+	}
 }
 
 func Recovery(log *zap.Logger) func(http.Handler) http.Handler {
@@ -57,12 +102,21 @@ func Recovery(log *zap.Logger) func(http.Handler) http.Handler {
 						zap.String("method", r.Method),
 					)
 
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write([]byte(`{"error": "Внутренняя ошибка сервера"}`))
+					writeJSONError(w, r, http.StatusInternalServerError, models.CodeInternal, "Внутренняя ошибка сервера")
 				}
 			}()
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// writeJSONError отвечает тем же конвертом ошибки, что и обработчики API.
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": i18n.Translate(i18n.FromContext(r.Context()), message),
+	})
+}
@@ -0,0 +1,9 @@
+package netguard
+
+import "errors"
+
+var (
+	ErrForbiddenAddress = errors.New("адрес запрещен политикой исходящих соединений")
+	ErrTooManyRedirects = errors.New("слишком много перенаправлений")
+	ErrInvalidRedirect  = errors.New("некорректное перенаправление")
+)
@@ -0,0 +1,150 @@
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 10
+
+// defaultDenied — адреса, недоступные по умолчанию: loopback, link-local,
+// частные сети RFC1918 и прочие специальные диапазоны. Префиксы трансляции NAT64 и
+// 6to4 закрыты целиком: внутри них может быть любой IPv4, в том числе внутренний.
+var defaultDenied = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// Guard проверяет адреса исходящих соединений.
+// Порядок проверки: deny список оператора, allow список оператора, встроенный deny список.
+type Guard struct {
+	allow    []netip.Prefix
+	deny     []netip.Prefix
+	resolver *net.Resolver
+}
+
+func New(allow, deny []netip.Prefix) *Guard {
+	return &Guard{
+		allow:    allow,
+		deny:     deny,
+		resolver: net.DefaultResolver,
+	}
+}
+
+func (g *Guard) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+
+	for _, p := range g.deny {
+		if p.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+		}
+	}
+	for _, p := range g.allow {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	for _, p := range defaultDenied {
+		if p.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+		}
+	}
+
+	return nil
+}
+
+// CheckHost резолвит хост и проверяет все его адреса.
+func (g *Guard) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.Check(addr)
+	}
+
+	addrs, err := g.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("не удалось разрешить хост %q: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if err := g.Check(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Control вызывается net.Dialer для каждого адреса непосредственно перед соединением,
+// поэтому проверяется именно тот IP, к которому идет подключение.
+func (g *Guard) Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	return g.Check(addr)
+}
+
+// CheckRedirect повторно проверяет каждый шаг перенаправления.
+func (g *Guard) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return ErrTooManyRedirects
+	}
+
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("%w: %s", ErrInvalidRedirect, req.URL.Redacted())
+	}
+
+	return g.CheckHost(req.Context(), req.URL.Hostname())
+}
+
+// NewClient создает HTTP клиент, который не подключается к запрещенным адресам.
+// Прокси из окружения не используется, иначе проверялся бы адрес прокси.
+func NewClient(timeout time.Duration, guard *Guard) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guard.Control,
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: guard.CheckRedirect,
+	}
+}
@@ -0,0 +1,110 @@
+package netguard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prefixes(values ...string) []netip.Prefix {
+	res := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		res = append(res, netip.MustParsePrefix(v))
+	}
+	return res
+}
+
+func TestGuard_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []netip.Prefix
+		deny    []netip.Prefix
+		addr    string
+		blocked bool
+	}{
+		{"public", nil, nil, "93.184.216.34", false},
+		{"loopback", nil, nil, "127.0.0.1", true},
+		{"metadata", nil, nil, "169.254.169.254", true},
+		{"rfc1918 10", nil, nil, "10.1.2.3", true},
+		{"rfc1918 172", nil, nil, "172.20.0.1", true},
+		{"rfc1918 192", nil, nil, "192.168.1.1", true},
+		{"unspecified", nil, nil, "0.0.0.0", true},
+		{"ipv6 loopback", nil, nil, "::1", true},
+		{"ipv6 link-local", nil, nil, "fe80::1", true},
+		{"ipv6 ula", nil, nil, "fd00::1", true},
+		{"ipv4-mapped loopback", nil, nil, "::ffff:127.0.0.1", true},
+		{"nat64 metadata", nil, nil, "64:ff9b::a9fe:a9fe", true},
+		{"nat64 public", nil, nil, "64:ff9b::5db8:d822", true},
+		{"local nat64", nil, nil, "64:ff9b:1::a00:1", true},
+		{"6to4 private", nil, nil, "2002:a00:1::1", true},
+		{"allowed nat64", prefixes("64:ff9b::/96"), nil, "64:ff9b::5db8:d822", false},
+		{"allowed private", prefixes("10.0.0.0/8"), nil, "10.1.2.3", false},
+		{"deny wins over allow", prefixes("10.0.0.0/8"), prefixes("10.1.0.0/16"), "10.1.2.3", true},
+		{"denied public", nil, prefixes("93.184.216.0/24"), "93.184.216.34", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := New(test.allow, test.deny).Check(netip.MustParseAddr(test.addr))
+			if test.blocked {
+				assert.ErrorIs(t, err, ErrForbiddenAddress)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewClient_BlocksLoopbackByDefault(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewClient(5*time.Second, New(nil, nil))
+	_, err := client.Get(ts.URL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	hostURL := strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)
+	_, err = client.Get(hostURL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
+
+func TestNewClient_AllowList(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewClient(5*time.Second, New(prefixes("127.0.0.1/32"), nil))
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestNewClient_RedirectRechecked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.2:1/internal", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	client := NewClient(5*time.Second, New(prefixes("127.0.0.1/32"), nil))
+	_, err := client.Get(ts.URL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
+
+func TestGuard_CheckRedirect_Scheme(t *testing.T) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "file:///etc/passwd", nil)
+	require.NoError(t, err)
+
+	err = New(nil, nil).CheckRedirect(req, []*http.Request{{}})
+	assert.ErrorIs(t, err, ErrInvalidRedirect)
+}
@@ -19,8 +19,9 @@ const (
 )
 
 type Server struct {
-	server *http.Server
-	logger *zap.Logger
+	server     *http.Server
+	logger     *zap.Logger
+	onShutdown []func(ctx context.Context) error
 }
 
 func New(port string, handler http.Handler, logger *zap.Logger) *Server {
@@ -35,6 +36,11 @@ func New(port string, handler http.Handler, logger *zap.Logger) *Server {
 	}
 }
 
+// OnShutdown регистрирует функцию, которая будет вызвана после остановки HTTP сервера.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
 func (s *Server) Start() error {
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
@@ -62,6 +68,17 @@ func (s *Server) Start() error {
 		}
 
 		s.logger.Info("HTTP сервер успешно остановлен")
+
+		var errs []error
+		for _, fn := range s.onShutdown {
+			if err := fn(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return fmt.Errorf("ошибка при остановке фоновых задач: %w", err)
+		}
+
 		return nil
 	}
 }
@@ -0,0 +1,129 @@
+package archive_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// errEntryMissing прерывает UpdateArchive без записи, если записи о файле уже нет.
+var errEntryMissing = errors.New("запись о файле не найдена")
+
+// fileErrorCode возвращает код ошибки скачивания. Порядок важен: ErrFileTruncated
+// приходит обернутым в ErrFileCopyFailed.
+func fileErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidFileURL):
+		return models.CodeInvalidFileURL
+	case errors.Is(err, ErrHostNotAllowed):
+		return models.CodeHostNotAllowed
+	case errors.Is(err, ErrUnsupportedFile):
+		return models.CodeUnsupportedFile
+	case errors.Is(err, ErrFileTooLarge):
+		return models.CodeFileTooLarge
+	case errors.Is(err, ErrArchiveTooLarge):
+		return models.CodeArchiveTooLarge
+	case errors.Is(err, ErrFileTruncated):
+		return models.CodeDownloadTruncated
+	case errors.Is(err, ErrFileDownloadFailed):
+		return models.CodeDownloadFailed
+	case errors.Is(err, ErrContextDone):
+		return models.CodeRequestCanceled
+	case errors.Is(err, ErrMkdirFailed), errors.Is(err, ErrFileCreateFailed), errors.Is(err, ErrFileOpenFailed),
+		errors.Is(err, ErrFileCopyFailed), errors.Is(err, ErrRemoveFailed):
+		return models.CodeFilesystemError
+	}
+	return models.CodeInternal
+}
+
+func pendingEntries(urls []string) []models.FileEntry {
+	entries := make([]models.FileEntry, len(urls))
+	for i, url := range urls {
+		entries[i] = models.FileEntry{URL: url, Status: models.FileStatusPending}
+	}
+	return entries
+}
+
+// failedEntry заполняет запись по ошибке скачивания.
+func failedEntry(entry models.FileEntry, err error) models.FileEntry {
+	entry.Status = models.FileStatusFailed
+	entry.ErrorCode = fileErrorCode(err)
+	entry.Error = err.Error()
+	return entry
+}
+
+// fetchedFile — скачанный файл во временной директории архива.
+type fetchedFile struct {
+	name   string
+	size   int64
+	mime   string
+	sha256 string
+}
+
+// fileDigest считает размер, SHA-256 и тип по первым байтам файла, пока тот пишется на
+// диск, чтобы не перечитывать файл после скачивания. При докачке счет продолжается с
+// места, где остановилась прошлая попытка.
+type fileDigest struct {
+	hash hash.Hash
+	head []byte
+	size int64
+}
+
+func newFileDigest() *fileDigest {
+	return &fileDigest{hash: sha256.New()}
+}
+
+func (d *fileDigest) Write(p []byte) (int, error) {
+	d.hash.Write(p)
+	if len(d.head) < sniffLen {
+		d.head = append(d.head, p[:min(len(p), sniffLen-len(d.head))]...)
+	}
+	d.size += int64(len(p))
+	return len(p), nil
+}
+
+func (d *fileDigest) file(name string) fetchedFile {
+	return fetchedFile{
+		name:   name,
+		size:   d.size,
+		mime:   sniffContentType(d.head),
+		sha256: hex.EncodeToString(d.hash.Sum(nil)),
+	}
+}
+
+// okEntry заполняет запись по скачанному файлу: размер, тип по содержимому и SHA-256.
+func okEntry(entry models.FileEntry, file fetchedFile) models.FileEntry {
+	entry.Status = models.FileStatusOK
+	entry.Name = file.name
+	entry.Size, entry.MIME, entry.SHA256 = file.size, file.mime, file.sha256
+	return entry
+}
+
+// markDownloading переводит запись о файле в статус downloading. Это только индикация
+// прогресса: ошибка сохранения не прерывает скачивание, итог записывается после него.
+func (s *archiveService) markDownloading(ctx context.Context, archiveID string, idx int, url string, started time.Time) {
+	_, err := s.repo.UpdateArchive(ctx, archiveID, func(a *models.Archive) error {
+		if idx >= len(a.Entries) || a.Entries[idx].URL != url {
+			return errEntryMissing
+		}
+		a.Entries[idx].Status = models.FileStatusDownloading
+		a.Entries[idx].StartedAt = started
+		return nil
+	})
+	// архив удаляют или сборку отменяют — тогда отмечать нечего
+	if err != nil && ctx.Err() == nil && !errors.Is(err, errEntryMissing) && !errors.Is(err, infra.ErrArchiveNotFound) {
+		s.logger.Warn("не удалось отметить начало скачивания файла",
+			zap.String("archive_id", archiveID),
+			zap.String("file_url", url),
+			zap.Error(err),
+		)
+	}
+}
@@ -5,22 +5,42 @@ import "errors"
 var (
 	ErrContextDone = errors.New("отмена контекста")
 
-	ErrServerBusy = errors.New("сервер занят, максимальное количество архивов в процессе достигнуто")
+	ErrServerBusy     = errors.New("сервер занят, максимальное количество архивов в процессе достигнуто")
+	ErrServiceStopped = errors.New("сервис остановлен")
+	ErrQueueFull      = errors.New("сервер перегружен, очередь сборки заполнена")
 
 	ErrMaxFilesPerArchive = errors.New("превышен лимит файлов в архиве")
 
-	ErrArchiveFull  = errors.New("архив заполнен")
-	ErrArchiveSave  = errors.New("не удалось сохранить архив")
-	ErrArchiveGet   = errors.New("не удалось получить архив")
-	ErrArchiveBuild = errors.New("не удалось создать архив")
+	ErrArchiveFull   = errors.New("архив заполнен")
+	ErrArchiveSave   = errors.New("не удалось сохранить архив")
+	ErrArchiveGet    = errors.New("не удалось получить архив")
+	ErrArchiveBuild  = errors.New("не удалось создать архив")
+	ErrArchiveDelete = errors.New("не удалось удалить архив")
+	ErrArchiveList   = errors.New("не удалось получить список архивов")
+
+	ErrInvalidListQuery = errors.New("некорректные параметры списка архивов")
 
 	ErrArchiveReady  = errors.New("невозможно добавить файл: архив уже собран")
 	ErrArchiveFailed = errors.New("невозможно добавить файл: архив не удалось собрать")
+	ErrArchiveJob    = errors.New("невозможно добавить файл: архив собирается по списку URL")
+
+	ErrFinalizeReady  = errors.New("невозможно завершить архив: архив уже собран")
+	ErrFinalizeFailed = errors.New("невозможно завершить архив: архив не удалось собрать")
+	ErrFinalizeJob    = errors.New("невозможно завершить архив: архив собирается по списку URL")
+	ErrArchiveEmpty   = errors.New("невозможно завершить архив: в архиве нет файлов")
+
+	ErrRemoveFromReady  = errors.New("невозможно удалить файл: архив уже собран")
+	ErrRemoveFromFailed = errors.New("невозможно удалить файл: архив не удалось собрать")
+	ErrRemoveFromJob    = errors.New("невозможно удалить файл: архив собирается по списку URL")
 
 	ErrFileNotFound       = errors.New("файл не найден")
 	ErrUnsupportedFile    = errors.New("неподдерживаемый файл")
 	ErrFileDownloadFailed = errors.New("не удалось загрузить файл")
 	ErrInvalidFileURL     = errors.New("некорректный URL файла")
+	ErrHostNotAllowed     = errors.New("хост источника запрещен политикой")
+	ErrFileTruncated      = errors.New("файл получен не полностью")
+	ErrFileTooLarge       = errors.New("файл превышает допустимый размер")
+	ErrArchiveTooLarge    = errors.New("превышен допустимый размер архива")
 
 	ErrMkdirFailed      = errors.New("не удалось создать директорию")
 	ErrFileCreateFailed = errors.New("не удалось создать файл")
@@ -0,0 +1,133 @@
+package archive_service
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultFileName = "file"
+	maxFileNameLen  = 200
+)
+
+var mimeExtensions = map[string][]string{
+	"application/pdf": {".pdf"},
+	"image/jpeg":      {".jpg", ".jpeg", ".jpe"},
+	"image/jpg":       {".jpg", ".jpeg", ".jpe"},
+	"image/png":       {".png"},
+	"image/gif":       {".gif"},
+	"application/zip": {".zip"},
+}
+
+// fileNameFromResponse выбирает имя файла: Content-Disposition (включая filename* по RFC 5987),
+// иначе последний сегмент пути URL без query. Расширение приводится к MIME типу файла.
+func fileNameFromResponse(rawURL string, header http.Header, mimeType string) string {
+	var name string
+
+	if cd := header.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			name = params["filename"]
+		}
+	}
+
+	if name == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			name = path.Base(u.Path)
+		}
+	}
+
+	name = sanitizeFileName(name)
+	if name == "" {
+		name = defaultFileName
+	}
+
+	return withExtension(name, mimeType)
+}
+
+func sanitizeFileName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError, unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(name, " .")
+
+	for len(name) > maxFileNameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name
+}
+
+func withExtension(name, mimeType string) string {
+	exts, ok := mimeExtensions[mediaType(mimeType)]
+	if !ok {
+		return name
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range exts {
+		if ext == allowed {
+			return name
+		}
+	}
+
+	return name + exts[0]
+}
+
+// fileNames раздает уникальные имена файлов внутри одного архива по схеме "name (2).pdf".
+type fileNames struct {
+	mu    sync.Mutex
+	taken map[string]struct{}
+}
+
+func newFileNames(existing []string) *fileNames {
+	n := &fileNames{taken: make(map[string]struct{}, len(existing))}
+	for _, name := range existing {
+		n.taken[strings.ToLower(name)] = struct{}{}
+	}
+	return n
+}
+
+func (n *fileNames) reserve(name string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := name
+	for i := 2; ; i++ {
+		if _, exists := n.taken[strings.ToLower(candidate)]; !exists {
+			break
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	n.taken[strings.ToLower(candidate)] = struct{}{}
+	return candidate
+}
+
+func (n *fileNames) release(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.taken, strings.ToLower(name))
+}
@@ -0,0 +1,134 @@
+package archive_service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// Finalize собирает zip из уже добавленных файлов, не дожидаясь заполнения архива.
+func (s *archiveService) Finalize(ctx context.Context, archiveID string) (*models.Archive, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	ctx, done := s.inflight.track(ctx, archiveID)
+	defer done()
+
+	unlock := s.locks.lock(archiveID)
+	defer unlock()
+
+	if s.inflight.deleted(archiveID) {
+		return nil, fmt.Errorf("%w: %w", ErrArchiveGet, infra.ErrArchiveNotFound)
+	}
+
+	archive, err := s.repo.GetArchive(ctx, archiveID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrArchiveGet, err)
+	}
+
+	switch {
+	case archive.Status == models.ArchiveStatusReady:
+		return nil, ErrFinalizeReady
+	case archive.Status == models.ArchiveStatusFailed:
+		return nil, ErrFinalizeFailed
+	case archive.Job:
+		return nil, ErrFinalizeJob
+	case len(archive.Files) == 0:
+		return nil, ErrArchiveEmpty
+	}
+
+	s.stopIdleTimer(archiveID)
+	s.buildArchive(ctx, archive)
+
+	archive, err = s.commitBuild(ctx, archive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveSave, err)
+	}
+
+	s.logger.Info("архив завершен",
+		zap.String("archive_id", archiveID),
+		zap.String("status", string(archive.Status)),
+		zap.Int("files", len(archive.Files)),
+	)
+	return archive, nil
+}
+
+// resetIdleTimer откладывает автоматическое завершение архива на ArchiveIdleFinalize
+// после последнего добавленного файла. Нулевой интервал отключает автозавершение.
+func (s *archiveService) resetIdleTimer(archiveID string) {
+	if s.cfg.ArchiveIdleFinalize <= 0 {
+		return
+	}
+
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+
+	if timer, ok := s.idle[archiveID]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(s.cfg.ArchiveIdleFinalize, func() {
+		s.finalizeIdle(archiveID, timer)
+	})
+	s.idle[archiveID] = timer
+}
+
+func (s *archiveService) stopIdleTimer(archiveID string) {
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+
+	if timer, ok := s.idle[archiveID]; ok {
+		timer.Stop()
+		delete(s.idle, archiveID)
+	}
+}
+
+func (s *archiveService) stopIdleTimers() {
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+
+	for archiveID, timer := range s.idle {
+		timer.Stop()
+		delete(s.idle, archiveID)
+	}
+}
+
+func (s *archiveService) finalizeIdle(archiveID string, timer *time.Timer) {
+	s.idleMu.Lock()
+	if s.idle[archiveID] != timer {
+		// таймер уже перезапущен новым файлом или остановлен
+		s.idleMu.Unlock()
+		return
+	}
+	delete(s.idle, archiveID)
+	s.idleMu.Unlock()
+
+	s.jobsMu.RLock()
+	if s.stopped {
+		s.jobsMu.RUnlock()
+		return
+	}
+	s.wg.Add(1)
+	s.jobsMu.RUnlock()
+	defer s.wg.Done()
+
+	if _, err := s.Finalize(s.baseCtx, archiveID); err != nil {
+		s.logger.Warn("не удалось автоматически завершить архив",
+			zap.String("archive_id", archiveID),
+			zap.Error(err),
+		)
+		return
+	}
+	s.logger.Info("архив завершен по таймауту бездействия",
+		zap.String("archive_id", archiveID),
+		zap.Duration("idle", s.cfg.ArchiveIdleFinalize),
+	)
+}
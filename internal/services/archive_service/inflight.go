@@ -0,0 +1,72 @@
+package archive_service
+
+import (
+	"context"
+	"sync"
+)
+
+// inflight хранит функции отмены работы, которая сейчас идет по каждому архиву:
+// фоновой сборки, AddFile и Finalize. Нужен, чтобы удаление архива прерывало скачивания.
+type inflight struct {
+	mu         sync.Mutex
+	next       uint64
+	cancels    map[string]map[uint64]context.CancelFunc
+	tombstones map[string]int
+}
+
+func newInflight() *inflight {
+	return &inflight{
+		cancels:    make(map[string]map[uint64]context.CancelFunc),
+		tombstones: make(map[string]int),
+	}
+}
+
+func (f *inflight) track(ctx context.Context, archiveID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	f.mu.Lock()
+	f.next++
+	token := f.next
+	if f.cancels[archiveID] == nil {
+		f.cancels[archiveID] = make(map[uint64]context.CancelFunc)
+	}
+	f.cancels[archiveID][token] = cancel
+	f.mu.Unlock()
+
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.cancels[archiveID], token)
+		if len(f.cancels[archiveID]) == 0 {
+			delete(f.cancels, archiveID)
+		}
+		f.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel отменяет работу по архиву и ставит отметку об удалении. Работа, начатая после
+// отмены, может взять блокировку архива раньше удаления, поэтому под блокировкой она
+// проверяет deleted. Возвращенная функция снимает отметку.
+func (f *inflight) cancel(archiveID string) (int, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tombstones[archiveID]++
+	for _, cancel := range f.cancels[archiveID] {
+		cancel()
+	}
+	return len(f.cancels[archiveID]), func() {
+		f.mu.Lock()
+		f.tombstones[archiveID]--
+		if f.tombstones[archiveID] == 0 {
+			delete(f.tombstones, archiveID)
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *inflight) deleted(archiveID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tombstones[archiveID] > 0
+}
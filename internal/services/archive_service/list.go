@@ -0,0 +1,78 @@
+package archive_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+var knownStatuses = []models.ArchiveStatus{
+	models.ArchiveStatusEmpty,
+	models.ArchiveStatusBuilding,
+	models.ArchiveStatusReady,
+	models.ArchiveStatusFailed,
+}
+
+// ListArchives возвращает страницу архивов. Размер страницы по умолчанию defaultListLimit,
+// больше maxListLimit не отдается.
+func (s *archiveService) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	if err := validateQuery(&query); err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.ListArchives(ctx, query)
+	if errors.Is(err, infra.ErrInvalidCursor) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidListQuery, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveList, err)
+	}
+
+	return page, nil
+}
+
+func validateQuery(query *models.ArchiveQuery) error {
+	for _, status := range query.Statuses {
+		if !slices.Contains(knownStatuses, status) {
+			return fmt.Errorf("%w: неизвестный статус %q", ErrInvalidListQuery, status)
+		}
+	}
+
+	switch query.SortBy {
+	case "":
+		query.SortBy = models.SortByCreatedAt
+	case models.SortByCreatedAt, models.SortByUpdatedAt:
+	default:
+		return fmt.Errorf("%w: неизвестное поле сортировки %q", ErrInvalidListQuery, query.SortBy)
+	}
+
+	if !query.CreatedFrom.IsZero() && !query.CreatedTo.IsZero() && !query.CreatedFrom.Before(query.CreatedTo) {
+		return fmt.Errorf("%w: начало интервала создания должно быть раньше конца", ErrInvalidListQuery)
+	}
+
+	switch {
+	case query.Limit < 0:
+		return fmt.Errorf("%w: отрицательный limit", ErrInvalidListQuery)
+	case query.Limit == 0:
+		query.Limit = defaultListLimit
+	case query.Limit > maxListLimit:
+		query.Limit = maxListLimit
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package archive_service
+
+import "sync"
+
+// archiveLocks сериализует изменения одного архива: добавление файла, сборку и завершение.
+type archiveLocks struct {
+	mu    sync.Mutex
+	locks map[string]*archiveLock
+}
+
+type archiveLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newArchiveLocks() *archiveLocks {
+	return &archiveLocks{locks: make(map[string]*archiveLock)}
+}
+
+func (l *archiveLocks) lock(archiveID string) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[archiveID]
+	if !ok {
+		lock = &archiveLock{}
+		l.locks[archiveID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, archiveID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,110 @@
+package archive_service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errRangeMismatch — источник ответил не тем диапазоном, который запрашивался.
+var errRangeMismatch = errors.New("источник не продолжил скачивание с нужного места")
+
+// partialFile — недокачанный .part файл, который переживает неудачную попытку
+// и продолжается следующей с помощью Range/If-Range. Живет в пределах одного вызова fetch.
+type partialFile struct {
+	names     *fileNames
+	filename  string
+	path      string
+	validator string
+	size      int64
+	digest    *fileDigest
+}
+
+func newPartialFile(names *fileNames) *partialFile {
+	return &partialFile{names: names}
+}
+
+func (p *partialFile) resumable() bool {
+	return p != nil && p.path != "" && p.validator != "" && p.size > 0 && p.digest != nil
+}
+
+func (p *partialFile) keep(path string, written int64, digest *fileDigest) {
+	p.path = path
+	p.size += written
+	p.digest = digest
+}
+
+// done сбрасывает состояние после того, как файл докачан и переименован.
+func (p *partialFile) done() {
+	if p == nil {
+		return
+	}
+	p.filename, p.path, p.validator, p.size = "", "", "", 0
+	p.digest = nil
+}
+
+// discard удаляет недокачанный файл и освобождает зарезервированное имя.
+func (p *partialFile) discard() {
+	if p == nil {
+		return
+	}
+	if p.path != "" {
+		os.Remove(p.path)
+	}
+	if p.filename != "" && p.names != nil {
+		p.names.release(p.filename)
+	}
+	p.done()
+}
+
+func (s *archiveService) resumeResponse(resp *http.Response, budget *sizeBudget, part *partialFile) (*streamReader, error) {
+	start, ok := contentRangeStart(resp.Header.Get("Content-Range"))
+	if resp.StatusCode != http.StatusPartialContent || !ok || start != part.size {
+		resp.Body.Close()
+		part.discard()
+		return nil, fmt.Errorf("%w: %w", ErrFileDownloadFailed, errRangeMismatch)
+	}
+
+	if !budget.reserve(part.size) {
+		resp.Body.Close()
+		part.discard()
+		return nil, fmt.Errorf("%w: лимит %d байт", ErrArchiveTooLarge, budget.limit)
+	}
+
+	stream := newStreamReader(resp.Body, resp.ContentLength, s.cfg.MaxFileSize, budget)
+	stream.resumeAt(part.size)
+	stream.validator = part.validator
+	return stream, nil
+}
+
+// resumeValidator возвращает значение для If-Range, если источник поддерживает докачку.
+// Слабый ETag для If-Range не подходит, поэтому в этом случае берется Last-Modified.
+func resumeValidator(header http.Header) string {
+	if header.Get("Accept-Ranges") != "bytes" {
+		return ""
+	}
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// contentRangeStart разбирает начало диапазона из "bytes 100-199/200".
+func contentRangeStart(value string) (int64, bool) {
+	rest, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil || start < 0 {
+		return 0, false
+	}
+	return start, true
+}
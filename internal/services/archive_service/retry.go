@@ -0,0 +1,172 @@
+package archive_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// httpStatusError — источник ответил кодом, отличным от 200.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d", e.StatusCode)
+}
+
+func newHTTPStatusError(resp *http.Response) *httpStatusError {
+	retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return &httpStatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter,
+	}
+}
+
+// fetch скачивает файл во временную директорию архива, повторяя попытку при временных сбоях.
+// Возвращает сохраненный файл и количество сделанных попыток.
+// Докачка .part работает только между попытками этого вызова: валидатор и имя живут в
+// part, поэтому после выхода недокачанный файл удаляется, и следующий вызов начинает с нуля.
+func (s *archiveService) fetch(ctx context.Context, archiveID, fileURL string, budget *sizeBudget, names *fileNames) (fetchedFile, int, error) {
+	maxAttempts := max(s.cfg.DownloadRetryAttempts, 1)
+	part := newPartialFile(names)
+	defer part.discard()
+
+	for attempt := 1; ; attempt++ {
+		s.logger.Info("попытка скачивания файла",
+			zap.String("archive_id", archiveID),
+			zap.String("file_url", fileURL),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", maxAttempts),
+		)
+
+		file, err := s.fetchOnce(ctx, archiveID, fileURL, budget, part)
+		if err == nil {
+			return file, attempt, nil
+		}
+
+		delay, retry := s.retryDelay(ctx, attempt, err)
+		retry = retry && attempt < maxAttempts
+		s.logger.Warn("попытка скачивания файла не удалась",
+			zap.String("archive_id", archiveID),
+			zap.String("file_url", fileURL),
+			zap.Int("attempt", attempt),
+			zap.Bool("retry", retry),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		if !retry {
+			return fetchedFile{}, attempt, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fetchedFile{}, attempt, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+		}
+	}
+}
+
+func (s *archiveService) fetchOnce(ctx context.Context, archiveID, fileURL string, budget *sizeBudget, part *partialFile) (fetchedFile, error) {
+	resuming := part.resumable()
+	stream, filename, err := s.download(ctx, fileURL, budget, part)
+	if err != nil {
+		return fetchedFile{}, err
+	}
+	defer stream.Close()
+
+	if !resuming || !part.resumable() {
+		if part.filename == "" {
+			part.filename = part.names.reserve(filename)
+		}
+		part.validator = stream.validator
+	}
+	filename = part.filename
+
+	file, err := s.writeFile(ctx, archiveID, filename, stream, part)
+	if err != nil {
+		if errors.Is(err, ErrFileTooLarge) || errors.Is(err, ErrArchiveTooLarge) {
+			return fetchedFile{}, err
+		}
+		return fetchedFile{}, fmt.Errorf("%w: %w", ErrFileCopyFailed, err)
+	}
+
+	return file, nil
+}
+
+// retryDelay решает, стоит ли повторять попытку, и считает паузу:
+// экспоненциальный рост от базовой задержки с джиттером или значение Retry-After.
+func (s *archiveService) retryDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if ctx.Err() != nil || !isTransient(err) {
+		return 0, false
+	}
+
+	maxDelay := s.cfg.DownloadRetryMaxDelay
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if maxDelay > 0 && statusErr.RetryAfter > maxDelay {
+			return statusErr.RetryAfter, false
+		}
+		return statusErr.RetryAfter, true
+	}
+
+	delay := s.cfg.DownloadRetryBaseDelay << (attempt - 1)
+	if delay <= 0 || (maxDelay > 0 && delay > maxDelay) {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0, true
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1), true
+}
+
+func isTransient(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrFileTruncated) || errors.Is(err, errRangeMismatch) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -2,42 +2,78 @@ package archive_service
 
 import (
 	"archive/zip"
-	"bytes"
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/hostpolicy"
 	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
 	"github.com/sunr3d/05-08-2025/internal/interfaces/services"
+	"github.com/sunr3d/05-08-2025/internal/netguard"
 	"github.com/sunr3d/05-08-2025/models"
 )
 
 var _ services.ArchiveService = (*archiveService)(nil)
 
+// partSuffix помечает файл, который еще скачивается.
+const partSuffix = ".part"
+
 type archiveService struct {
 	repo       infra.Database
 	logger     *zap.Logger
 	cfg        *config.Config
 	httpClient *http.Client
+	hosts      *hostpolicy.Policy
+
+	jobs        chan buildJob
+	jobsMu      sync.RWMutex
+	stopped     bool
+	wg          sync.WaitGroup
+	baseCtx     context.Context
+	cancel      context.CancelFunc
+	downloadSem chan struct{}
+	locks       *archiveLocks
+	inflight    *inflight
+
+	idleMu sync.Mutex
+	idle   map[string]*time.Timer
 }
 
 func New(log *zap.Logger, cfg *config.Config, repo infra.Database) services.ArchiveService {
-	return &archiveService{
-		logger:     log,
-		cfg:        cfg,
-		repo:       repo,
-		httpClient: &http.Client{Timeout: cfg.HTTPTimeout},
-	}
+	baseCtx, cancel := context.WithCancel(context.Background())
+
+	s := &archiveService{
+		logger:      log,
+		cfg:         cfg,
+		repo:        repo,
+		httpClient:  netguard.NewClient(cfg.HTTPTimeout, netguard.New(cfg.SSRFAllowCIDRs, cfg.SSRFDenyCIDRs)),
+		hosts:       hostpolicy.New(cfg.AllowedHosts, cfg.DeniedHosts),
+		jobs:        make(chan buildJob, max(cfg.WorkerQueueSize, 1)),
+		baseCtx:     baseCtx,
+		cancel:      cancel,
+		downloadSem: make(chan struct{}, max(cfg.MaxParallelDownloads, 1)),
+		locks:       newArchiveLocks(),
+		inflight:    newInflight(),
+		idle:        make(map[string]*time.Timer),
+	}
+	s.httpClient.CheckRedirect = s.checkRedirect(s.httpClient.CheckRedirect)
+	s.startWorkers(max(cfg.WorkersCount, 1))
+
+	return s
 }
 
 func (s *archiveService) CreateArchive(ctx context.Context, urls []string) (*models.Archive, error) {
@@ -47,15 +83,6 @@ func (s *archiveService) CreateArchive(ctx context.Context, urls []string) (*mod
 	default:
 	}
 
-	limit, err := s.repo.CountArchivesInProcess(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось получить количество архивов в процессе сборки: %w", err)
-	}
-
-	if limit >= s.cfg.MaxArchivesInProcess {
-		return nil, ErrServerBusy
-	}
-
 	if len(urls) > s.cfg.MaxFilesPerArchive {
 		return nil, fmt.Errorf("%w: %v", ErrMaxFilesPerArchive, len(urls))
 	}
@@ -68,69 +95,28 @@ func (s *archiveService) CreateArchive(ctx context.Context, urls []string) (*mod
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Errors:    make([]string, 0, len(urls)),
+		Job:       true,
+		Entries:   pendingEntries(urls),
 	}
 
-	for _, url := range urls {
-		if !s.isValidURL(url) {
-			archive.Errors = append(archive.Errors, fmt.Sprintf("%s - %s", url, ErrInvalidFileURL.Error()))
-			continue
-		}
-
-		fileReader, filename, err := s.downloadFile(ctx, url)
-		if err != nil {
-			archive.Errors = append(archive.Errors, fmt.Sprintf("%s - %s", url, err.Error()))
-			continue
-		}
-
-		func() {
-			defer fileReader.Close()
-			if err := s.saveFile(ctx, archiveID, filename, fileReader); err != nil {
-				archive.Errors = append(archive.Errors, fmt.Sprintf("%s - %s", url, err.Error()))
-				return
-			}
-			archive.Files = append(archive.Files, filename)
-		}()
+	if err := s.insert(ctx, archive); err != nil {
+		return nil, err
 	}
 
-	if len(archive.Files) > 0 {
-		if err := s.buildZip(ctx, archiveID, archive.Files); err != nil {
-			archive.Status = models.ArchiveStatusFailed
-			archive.Errors = append(archive.Errors, fmt.Sprintf("%s: %s", ErrArchiveBuild.Error(), err.Error()))
-		} else {
-			archive.Status = models.ArchiveStatusReady
-			if err := s.cleanupTemp(ctx, archiveID); err != nil {
-				s.logger.Error("не удалось очистить временные файлы",
-					zap.String("archive_id", archiveID),
-					zap.Error(err),
-				)
-			}
+	if err := s.enqueue(buildJob{archiveID: archiveID, urls: urls}); err != nil {
+		if delErr := s.repo.DeleteArchive(ctx, archiveID); delErr != nil {
+			s.logger.Error("не удалось удалить архив после ошибки постановки в очередь",
+				zap.String("archive_id", archiveID),
+				zap.Error(delErr),
+			)
 		}
-	} else {
-		archive.Status = models.ArchiveStatusFailed
+		return nil, err
 	}
 
-	err = s.repo.SaveArchive(ctx, archive)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrArchiveSave, err)
-	}
-
-	if len(archive.Files) > 0 {
-		s.logger.Info("архив собран",
-			zap.String("archive_id", archive.ID),
-			zap.String("status", string(archive.Status)),
-			zap.Int("total_urls", len(urls)),
-			zap.Int("successful_files", len(archive.Files)),
-			zap.Int("errors", len(archive.Errors)),
-		)
-	} else {
-		s.logger.Info("архив не создан, нет доступных файлов",
-			zap.String("archive_id", archive.ID),
-			zap.String("status", string(archive.Status)),
-			zap.Int("total_urls", len(urls)),
-			zap.Int("successful_files", len(archive.Files)),
-			zap.Int("errors", len(archive.Errors)),
-		)
-	}
+	s.logger.Info("архив поставлен в очередь на сборку",
+		zap.String("archive_id", archive.ID),
+		zap.Int("total_urls", len(urls)),
+	)
 	return archive, nil
 }
 
@@ -141,15 +127,6 @@ func (s *archiveService) CreateEmptyArchive(ctx context.Context) (*models.Archiv
 	default:
 	}
 
-	limit, err := s.repo.CountArchivesInProcess(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось получить количество архивов в процессе сборки: %w", err)
-	}
-
-	if limit >= s.cfg.MaxArchivesInProcess {
-		return nil, ErrServerBusy
-	}
-
 	archiveID := uuid.New().String()
 	archive := &models.Archive{
 		ID:        archiveID,
@@ -160,15 +137,27 @@ func (s *archiveService) CreateEmptyArchive(ctx context.Context) (*models.Archiv
 		Errors:    make([]string, 0, s.cfg.MaxFilesPerArchive),
 	}
 
-	err = s.repo.SaveArchive(ctx, archive)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrArchiveSave, err)
+	if err := s.insert(ctx, archive); err != nil {
+		return nil, err
 	}
 
 	s.logger.Info("пустой архив создан", zap.String("archive_id", archive.ID))
 	return archive, nil
 }
 
+// insert сохраняет новый архив, если не превышен MaxArchivesInProcess.
+// Лимит проверяется хранилищем атомарно вместе с записью.
+func (s *archiveService) insert(ctx context.Context, archive *models.Archive) error {
+	err := s.repo.InsertArchive(ctx, archive, s.cfg.MaxArchivesInProcess)
+	if errors.Is(err, infra.ErrLimitReached) {
+		return ErrServerBusy
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveSave, err)
+	}
+	return nil
+}
+
 func (s *archiveService) AddFile(ctx context.Context, archiveID, fileURL string) error {
 	select {
 	case <-ctx.Done():
@@ -176,81 +165,276 @@ func (s *archiveService) AddFile(ctx context.Context, archiveID, fileURL string)
 	default:
 	}
 
-	archive, err := s.repo.GetArchive(ctx, archiveID)
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrArchiveGet, err)
-	}
+	ctx, done := s.inflight.track(ctx, archiveID)
+	defer done()
 
-	if archive.Status == models.ArchiveStatusReady {
-		return ErrArchiveReady
+	unlock := s.locks.lock(archiveID)
+	defer unlock()
+
+	if s.inflight.deleted(archiveID) {
+		return fmt.Errorf("%w: %w", ErrArchiveGet, infra.ErrArchiveNotFound)
 	}
-	if archive.Status == models.ArchiveStatusFailed {
-		return ErrArchiveFailed
+
+	archive, err := s.repo.GetArchive(ctx, archiveID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveGet, err)
 	}
 
-	if len(archive.Files) >= s.cfg.MaxFilesPerArchive {
-		return ErrArchiveFull
+	if err := s.checkAddable(archive); err != nil {
+		return err
 	}
 
 	if !s.isValidURL(fileURL) {
 		return ErrInvalidFileURL
 	}
 
-	fileReader, filename, err := s.downloadFile(ctx, fileURL)
-	if err != nil {
-		s.logger.Error("не удалось загрузить файл",
+	if err := s.checkHost(fileURL); err != nil {
+		s.logger.Warn("хост источника отклонен политикой",
 			zap.String("archive_id", archiveID),
 			zap.String("file_url", fileURL),
 			zap.Error(err),
 		)
-		return fmt.Errorf("%w: %v", ErrFileDownloadFailed, err)
+		return err
 	}
-	defer fileReader.Close()
 
-	if err := s.saveFile(ctx, archiveID, filename, fileReader); err != nil {
-		s.logger.Error("не удалось сохранить файл",
+	release, err := acquire(ctx, s.downloadSem)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	budget := newSizeBudget(s.cfg.MaxArchiveSize, s.tempFilesSize(archiveID, archive.Files))
+	started := time.Now()
+	file, attempts, err := s.fetch(ctx, archiveID, fileURL, budget, newFileNames(archive.Files))
+	if err != nil {
+		s.logger.Error("не удалось загрузить файл",
 			zap.String("archive_id", archiveID),
 			zap.String("file_url", fileURL),
+			zap.Int("attempts", attempts),
 			zap.Error(err),
 		)
-		return fmt.Errorf("%w: %v", ErrFileCopyFailed, err)
+		return err
 	}
 
-	archive.Files = append(archive.Files, filename)
-	archive.UpdatedAt = time.Now()
-	if archive.Status == models.ArchiveStatusEmpty {
-		archive.Status = models.ArchiveStatusBuilding
+	// запись о файле появляется только после успешного скачивания: ошибку клиент получает в ответе
+	entry := okEntry(models.FileEntry{
+		URL:        fileURL,
+		Attempts:   attempts,
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+	}, file)
+
+	// пока файл скачивался, архив мог измениться: проверки повторяются на свежей записи
+	archive, err = s.repo.UpdateArchive(ctx, archiveID, func(a *models.Archive) error {
+		if err := s.checkAddable(a); err != nil {
+			return err
+		}
+		if a.Attempts == nil {
+			a.Attempts = make(map[string]int)
+		}
+		a.Attempts[fileURL] += attempts
+		a.Files = append(a.Files, file.name)
+		a.Entries = append(a.Entries, entry)
+		a.UpdatedAt = time.Now()
+		if a.Status == models.ArchiveStatusEmpty {
+			a.Status = models.ArchiveStatusBuilding
+		}
+		return nil
+	})
+	if err != nil {
+		if rmErr := os.Remove(filepath.Join(s.cfg.TempDir, archiveID, file.name)); rmErr != nil && !os.IsNotExist(rmErr) {
+			s.logger.Error("не удалось удалить скачанный файл",
+				zap.String("archive_id", archiveID),
+				zap.String("filename", file.name),
+				zap.Error(rmErr),
+			)
+		}
+		switch {
+		case errors.Is(err, ErrArchiveReady), errors.Is(err, ErrArchiveFailed), errors.Is(err, ErrArchiveFull):
+			return err
+		case errors.Is(err, infra.ErrArchiveNotFound):
+			return fmt.Errorf("%w: %w", ErrArchiveGet, err)
+		default:
+			return fmt.Errorf("%w: %v", ErrArchiveSave, err)
+		}
 	}
 	s.logger.Info("файл добавлен в архив",
 		zap.String("archive_id", archiveID),
-		zap.String("filename", filename),
+		zap.String("filename", file.name),
 		zap.String("archive_status", string(archive.Status)),
 	)
 
 	if len(archive.Files) == s.cfg.MaxFilesPerArchive {
-		if err := s.buildZip(ctx, archiveID, archive.Files); err != nil {
-			archive.Status = models.ArchiveStatusFailed
-			archive.Errors = append(archive.Errors, fmt.Sprintf("%s: %s", ErrArchiveBuild.Error(), err.Error()))
-		} else {
-			archive.Status = models.ArchiveStatusReady
-			if err := s.cleanupTemp(ctx, archiveID); err != nil {
-				s.logger.Error("не удалось очистить временные файлы",
-					zap.String("archive_id", archiveID),
-					zap.Error(err),
-				)
-			}
+		s.stopIdleTimer(archiveID)
+		s.buildArchive(ctx, archive)
+		if _, err := s.commitBuild(ctx, archive); err != nil {
+			return fmt.Errorf("%w: %v", ErrArchiveSave, err)
+		}
+		if archive.Status == models.ArchiveStatusReady {
 			s.logger.Info("архив собран", zap.String("archive_id", archiveID))
 		}
+		return nil
 	}
 
-	err = s.repo.SaveArchive(ctx, archive)
-	if err != nil {
+	s.resetIdleTimer(archiveID)
+	return nil
+}
+
+// checkAddable проверяет, что в архив еще можно добавить файл.
+func (s *archiveService) checkAddable(archive *models.Archive) error {
+	switch {
+	case archive.Status == models.ArchiveStatusReady:
+		return ErrArchiveReady
+	case archive.Status == models.ArchiveStatusFailed:
+		return ErrArchiveFailed
+	case archive.Job:
+		return ErrArchiveJob
+	case len(archive.Files) >= s.cfg.MaxFilesPerArchive:
+		return ErrArchiveFull
+	}
+	return nil
+}
+
+// commitBuild сохраняет результат buildArchive: статус и ошибки сборки.
+func (s *archiveService) commitBuild(ctx context.Context, built *models.Archive) (*models.Archive, error) {
+	return s.repo.UpdateArchive(ctx, built.ID, func(a *models.Archive) error {
+		a.Status = built.Status
+		a.Errors = built.Errors
+		a.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// DeleteArchive прерывает скачивание и сборку архива, удаляет его временные файлы,
+// готовый zip и запись в хранилище.
+func (s *archiveService) DeleteArchive(ctx context.Context, archiveID string) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	canceled, release := s.inflight.cancel(archiveID)
+	if canceled > 0 {
+		s.logger.Info("работа по архиву отменена",
+			zap.String("archive_id", archiveID),
+			zap.Int("canceled", canceled),
+		)
+	}
+
+	unlock := s.locks.lock(archiveID)
+	defer unlock()
+	defer release()
+
+	if _, err := s.repo.GetArchive(ctx, archiveID); err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveGet, err)
+	}
+
+	s.stopIdleTimer(archiveID)
+
+	if err := os.RemoveAll(filepath.Join(s.cfg.TempDir, archiveID)); err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoveFailed, err)
+	}
+	if err := os.Remove(filepath.Join(s.cfg.ArchivesDir, archiveID+".zip")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %v", ErrRemoveFailed, err)
+	}
+
+	if err := s.repo.DeleteArchive(ctx, archiveID); err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveDelete, err)
+	}
+
+	s.logger.Info("архив удален", zap.String("archive_id", archiveID))
+	return nil
+}
+
+// RemoveFile убирает файл из еще не собранного архива вместе с его временной копией.
+func (s *archiveService) RemoveFile(ctx context.Context, archiveID, filename string) error {
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	default:
+	}
+
+	unlock := s.locks.lock(archiveID)
+	defer unlock()
+
+	archive, err := s.repo.UpdateArchive(ctx, archiveID, func(a *models.Archive) error {
+		switch a.Status {
+		case models.ArchiveStatusReady:
+			return ErrRemoveFromReady
+		case models.ArchiveStatusFailed:
+			return ErrRemoveFromFailed
+		}
+		if a.Job {
+			return ErrRemoveFromJob
+		}
+
+		idx := slices.Index(a.Files, filename)
+		if idx < 0 {
+			return fmt.Errorf("%w: %s", ErrFileNotFound, filename)
+		}
+
+		a.Files = slices.Delete(a.Files, idx, idx+1)
+		a.Entries = slices.DeleteFunc(a.Entries, func(e models.FileEntry) bool {
+			return e.Status == models.FileStatusOK && e.Name == filename
+		})
+		a.UpdatedAt = time.Now()
+		if len(a.Files) == 0 {
+			a.Status = models.ArchiveStatusEmpty
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRemoveFromReady), errors.Is(err, ErrRemoveFromFailed), errors.Is(err, ErrRemoveFromJob),
+		errors.Is(err, ErrFileNotFound):
+		return err
+	case errors.Is(err, infra.ErrArchiveNotFound):
+		return fmt.Errorf("%w: %w", ErrArchiveGet, err)
+	default:
 		return fmt.Errorf("%w: %v", ErrArchiveSave, err)
 	}
 
+	filePath := filepath.Join(s.cfg.TempDir, archiveID, filename)
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %v", ErrRemoveFailed, err)
+	}
+
+	if len(archive.Files) == 0 {
+		s.stopIdleTimer(archiveID)
+	} else {
+		s.resetIdleTimer(archiveID)
+	}
+
+	s.logger.Info("файл удален из архива",
+		zap.String("archive_id", archiveID),
+		zap.String("filename", filename),
+		zap.Int("files_left", len(archive.Files)),
+	)
 	return nil
 }
 
+func (s *archiveService) buildArchive(ctx context.Context, archive *models.Archive) {
+	if len(archive.Files) == 0 {
+		archive.Status = models.ArchiveStatusFailed
+		return
+	}
+
+	if err := s.buildZip(ctx, archive.ID, archive.Files); err != nil {
+		archive.Status = models.ArchiveStatusFailed
+		archive.Errors = append(archive.Errors, fmt.Sprintf("%s: %s", ErrArchiveBuild.Error(), err.Error()))
+		return
+	}
+
+	archive.Status = models.ArchiveStatusReady
+	if err := s.cleanupTemp(ctx, archive.ID); err != nil {
+		s.logger.Error("не удалось очистить временные файлы",
+			zap.String("archive_id", archive.ID),
+			zap.Error(err),
+		)
+	}
+}
+
 func (s *archiveService) GetArchive(ctx context.Context, archiveID string) (*models.Archive, error) {
 	select {
 	case <-ctx.Done():
@@ -260,7 +444,7 @@ func (s *archiveService) GetArchive(ctx context.Context, archiveID string) (*mod
 
 	archive, err := s.repo.GetArchive(ctx, archiveID)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrArchiveGet, err)
+		return nil, fmt.Errorf("%w: %w", ErrArchiveGet, err)
 	}
 
 	s.logger.Info("статус архива получен",
@@ -275,9 +459,30 @@ func (s *archiveService) isValidURL(url string) bool {
 	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
 }
 
+func (s *archiveService) checkHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFileURL, err)
+	}
+
+	if err := s.hosts.Check(u.Hostname()); err != nil {
+		return fmt.Errorf("%w: %w", ErrHostNotAllowed, err)
+	}
+
+	return nil
+}
+
+func (s *archiveService) checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := next(req, via); err != nil {
+			return err
+		}
+		return s.checkHost(req.URL.String())
+	}
+}
+
 func (s *archiveService) isValidExt(contentType string) bool {
-	contentType = strings.Split(contentType, ";")[0]
-	contentType = strings.TrimSpace(contentType)
+	contentType = mediaType(contentType)
 
 	for _, allowed := range s.cfg.AllowedExtensions {
 		if contentType == allowed {
@@ -287,61 +492,145 @@ func (s *archiveService) isValidExt(contentType string) bool {
 	return false
 }
 
-func (s *archiveService) downloadFile(ctx context.Context, url string) (io.ReadCloser, string, error) {
+// download запрашивает файл. Если part содержит недокачанный файл, запрос продолжает его
+// с помощью Range/If-Range; если источник вернул файл целиком, часть отбрасывается.
+func (s *archiveService) download(ctx context.Context, url string, budget *sizeBudget, part *partialFile) (*streamReader, string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("%w: %v", ErrInvalidFileURL, err)
 	}
 
+	resuming := part.resumable()
+	if resuming {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", part.size))
+		req.Header.Set("If-Range", part.validator)
+	}
+
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", ErrFileDownloadFailed, err)
+		return nil, "", fmt.Errorf("%w: %w", ErrFileDownloadFailed, err)
 	}
-	defer resp.Body.Close()
 
+	if resuming {
+		if resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			stream, err := s.resumeResponse(resp, budget, part)
+			return stream, part.filename, err
+		}
+		part.discard()
+	}
+
+	return s.openResponse(url, resp, budget)
+}
+
+func (s *archiveService) openResponse(url string, resp *http.Response, budget *sizeBudget) (*streamReader, string, error) {
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("%w: HTTP status %d", ErrFileDownloadFailed, resp.StatusCode)
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("%w: %w", ErrFileDownloadFailed, newHTTPStatusError(resp))
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !s.isValidExt(contentType) {
+	if s.cfg.ContentCheckMode != config.ContentCheckSniff && !s.isValidExt(contentType) {
+		resp.Body.Close()
 		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedFile, contentType)
 	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", ErrFileDownloadFailed, err)
+	body := bufio.NewReaderSize(resp.Body, sniffLen)
+	head, _ := body.Peek(sniffLen)
+	sniffedType := sniffContentType(head)
+	if !s.isAllowedContent(contentType, sniffedType) {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("%w: %s (по содержимому: %s)", ErrUnsupportedFile, contentType, sniffedType)
 	}
 
-	filename := path.Base(url)
-	return io.NopCloser(bytes.NewReader(data)), filename, nil
+	if s.cfg.MaxFileSize > 0 && resp.ContentLength > s.cfg.MaxFileSize {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("%w: %d байт, лимит %d байт", ErrFileTooLarge, resp.ContentLength, s.cfg.MaxFileSize)
+	}
+	if resp.ContentLength > 0 && !budget.fits(resp.ContentLength) {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("%w: лимит %d байт", ErrArchiveTooLarge, budget.limit)
+	}
+
+	fileType := contentType
+	if s.cfg.ContentCheckMode == config.ContentCheckSniff || s.cfg.ContentCheckMode == config.ContentCheckBoth {
+		fileType = sniffedType
+	}
+
+	filename := fileNameFromResponse(url, resp.Header, fileType)
+	stream := newStreamReader(struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}, resp.ContentLength, s.cfg.MaxFileSize, budget)
+	stream.validator = resumeValidator(resp.Header)
+	return stream, filename, nil
 }
 
-func (s *archiveService) saveFile(ctx context.Context, archiveID, filename string, fileReader io.ReadCloser) error {
+// writeFile пишет поток в .part файл и переименовывает его после успешного скачивания.
+// Размер, тип и SHA-256 файла считаются по ходу записи.
+// Если part не nil и источник поддерживает докачку, недокачанный файл остается на диске.
+func (s *archiveService) writeFile(ctx context.Context, archiveID, filename string, fileReader io.ReadCloser, part *partialFile) (fetchedFile, error) {
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+		return fetchedFile{}, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
 	default:
 	}
 
 	dir := filepath.Join(s.cfg.TempDir, archiveID)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("%w: %v", ErrMkdirFailed, err)
+		return fetchedFile{}, fmt.Errorf("%w: %v", ErrMkdirFailed, err)
 	}
 
 	filePath := filepath.Join(dir, filename)
-	file, err := os.Create(filePath)
+	partPath := filePath + partSuffix
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	digest := newFileDigest()
+	if part.resumable() {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		digest = part.digest
+	}
+	file, err := os.OpenFile(partPath, flags, 0644)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrFileCreateFailed, err)
+		return fetchedFile{}, fmt.Errorf("%w: %v", ErrFileCreateFailed, err)
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, fileReader)
+	written, err := io.Copy(io.MultiWriter(file, digest), fileReader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrFileCopyFailed, err)
+		sizeErr := errors.Is(err, ErrFileTooLarge) || errors.Is(err, ErrArchiveTooLarge)
+		if part != nil && part.validator != "" && !sizeErr {
+			part.keep(partPath, written, digest)
+		} else {
+			os.Remove(partPath)
+			part.discard()
+		}
+		if sizeErr {
+			return fetchedFile{}, err
+		}
+		return fetchedFile{}, fmt.Errorf("%w: %w", ErrFileCopyFailed, err)
 	}
 
-	return nil
+	if err := os.Rename(partPath, filePath); err != nil {
+		os.Remove(partPath)
+		part.discard()
+		return fetchedFile{}, fmt.Errorf("%w: %v", ErrFileCreateFailed, err)
+	}
+	part.done()
+
+	return digest.file(filename), nil
+}
+
+func (s *archiveService) tempFilesSize(archiveID string, files []string) int64 {
+	var total int64
+	for _, filename := range files {
+		info, err := os.Stat(filepath.Join(s.cfg.TempDir, archiveID, filename))
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+	}
+	return total
 }
 
 func (s *archiveService) buildZip(ctx context.Context, archiveID string, files []string) error {
@@ -1,14 +1,23 @@
 package archive_service
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,21 +26,63 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"github.com/sunr3d/05-08-2025/internal/config"
+	"github.com/sunr3d/05-08-2025/internal/hostpolicy"
 	"github.com/sunr3d/05-08-2025/internal/infra/inmem"
+	"github.com/sunr3d/05-08-2025/internal/interfaces/infra"
+	"github.com/sunr3d/05-08-2025/internal/netguard"
 	"github.com/sunr3d/05-08-2025/models"
 
 	"github.com/stretchr/testify/mock"
 	mocks "github.com/sunr3d/05-08-2025/mocks"
 )
 
-const (
-	testPDFURL  = "https://www.w3.org/WAI/ER/tests/xhtml/testfiles/resources/pdf/dummy.pdf"
-	testJPEGURL = "https://httpbin.org/image/jpeg"
-	testPNG     = "https://httpbin.org/image/png"
-	invalidURL  = "invalid-url"
-	notFoundURL = "https://httpbin.org/status/404"
+const invalidURL = "invalid-url"
+
+var (
+	testPDFURL  string
+	testJPEGURL string
+	testPNG     string
+	notFoundURL string
+)
+
+var (
+	testPDFData  = []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	testJPEGData = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0xFF, 0xD9}
+	testPNGData  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0x00, 0x00, 0x00, 0x0D}
 )
 
+func TestMain(m *testing.M) {
+	ts := newFixtureServer()
+	testPDFURL = ts.URL + "/dummy.pdf"
+	testJPEGURL = ts.URL + "/image/jpeg"
+	testPNG = ts.URL + "/image/png"
+	notFoundURL = ts.URL + "/status/404"
+
+	code := m.Run()
+	ts.Close()
+	os.Exit(code)
+}
+
+func newFixtureServer() *httptest.Server {
+	serve := func(contentType string, data []byte) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dummy.pdf", serve("application/pdf", testPDFData))
+	mux.HandleFunc("/image/jpeg", serve("image/jpeg", testJPEGData))
+	mux.HandleFunc("/image/png", serve("image/png", testPNGData))
+	mux.HandleFunc("/status/404", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	return httptest.NewServer(mux)
+}
+
 func setupTestService(t *testing.T) (*archiveService, func()) {
 	logger := zaptest.NewLogger(t)
 
@@ -47,18 +98,44 @@ func setupTestService(t *testing.T) (*archiveService, func()) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempFilesDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+		WorkersCount:         2,
+		WorkerQueueSize:      4,
+		DownloadsPerArchive:  3,
+		MaxParallelDownloads: 10,
+
+		DownloadRetryAttempts:  3,
+		DownloadRetryBaseDelay: time.Millisecond,
+		DownloadRetryMaxDelay:  10 * time.Millisecond,
 	}
 
 	repo := inmem.New(logger, cfg.ArchiveTTL)
 	service := New(logger, cfg, repo).(*archiveService)
 
 	cleanup := func() {
+		service.Shutdown(context.Background())
 		os.RemoveAll(tempDir)
 	}
 
 	return service, cleanup
 }
 
+func waitForArchive(t *testing.T, service *archiveService, archiveID string) *models.Archive {
+	t.Helper()
+
+	var archive *models.Archive
+	require.Eventually(t, func() bool {
+		a, err := service.GetArchive(context.Background(), archiveID)
+		if err != nil {
+			return false
+		}
+		archive = a
+		return a.Status != models.ArchiveStatusBuilding
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return archive
+}
+
 func TestArchiveService_CreateArchive_Success(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
@@ -66,9 +143,11 @@ func TestArchiveService_CreateArchive_Success(t *testing.T) {
 	ctx := context.Background()
 	urls := []string{testPDFURL}
 
-	archive, err := service.CreateArchive(ctx, urls)
-
+	created, err := service.CreateArchive(ctx, urls)
 	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, created.Status)
+
+	archive := waitForArchive(t, service, created.ID)
 	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
 	assert.Len(t, archive.Files, 1)
 	assert.Empty(t, archive.Errors)
@@ -86,9 +165,11 @@ func TestArchiveService_CreateArchive_PartialSuccess(t *testing.T) {
 	ctx := context.Background()
 	urls := []string{testPDFURL, invalidURL}
 
-	archive, err := service.CreateArchive(ctx, urls)
-
+	created, err := service.CreateArchive(ctx, urls)
 	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, created.Status)
+
+	archive := waitForArchive(t, service, created.ID)
 	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
 	assert.Len(t, archive.Files, 1)
 	assert.Len(t, archive.Errors, 1)
@@ -98,6 +179,318 @@ func TestArchiveService_CreateArchive_PartialSuccess(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestArchiveService_CreateArchive_Entries(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	urls := []string{testPDFURL, invalidURL, notFoundURL}
+
+	created, err := service.CreateArchive(ctx, urls)
+	require.NoError(t, err)
+	require.Len(t, created.Entries, 3)
+	for i, entry := range created.Entries {
+		assert.Equal(t, urls[i], entry.URL)
+		assert.Equal(t, models.FileStatusPending, entry.Status)
+	}
+
+	archive := waitForArchive(t, service, created.ID)
+	require.Len(t, archive.Entries, 3)
+
+	ok := archive.Entries[0]
+	sum := sha256.Sum256(testPDFData)
+	assert.Equal(t, models.FileStatusOK, ok.Status)
+	assert.Equal(t, archive.Files[0], ok.Name)
+	assert.EqualValues(t, len(testPDFData), ok.Size)
+	assert.Equal(t, "application/pdf", ok.MIME)
+	assert.Equal(t, hex.EncodeToString(sum[:]), ok.SHA256)
+	assert.Equal(t, 1, ok.Attempts)
+	assert.False(t, ok.StartedAt.IsZero())
+	assert.False(t, ok.FinishedAt.Before(ok.StartedAt))
+	assert.Empty(t, ok.ErrorCode)
+
+	assert.Equal(t, models.FileStatusFailed, archive.Entries[1].Status)
+	assert.Equal(t, models.CodeInvalidFileURL, archive.Entries[1].ErrorCode)
+	assert.Equal(t, ErrInvalidFileURL.Error(), archive.Entries[1].Error)
+
+	assert.Equal(t, models.FileStatusFailed, archive.Entries[2].Status)
+	assert.Equal(t, models.CodeDownloadFailed, archive.Entries[2].ErrorCode)
+	assert.Empty(t, archive.Entries[2].Name)
+}
+
+func TestArchiveService_CreateArchive_EntryDownloading(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(testPDFData)
+	}))
+	defer ts.Close()
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	defer releaseOnce()
+
+	created, err := service.CreateArchive(context.Background(), []string{ts.URL + "/slow.pdf"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		a, err := service.GetArchive(context.Background(), created.ID)
+		return err == nil && a.Entries[0].Status == models.FileStatusDownloading && !a.Entries[0].StartedAt.IsZero()
+	}, 5*time.Second, 10*time.Millisecond)
+	releaseOnce()
+
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.FileStatusOK, archive.Entries[0].Status)
+}
+
+func TestArchiveService_CreateArchive_ReturnsBeforeDownload(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	created, err := service.CreateArchive(ctx, []string{ts.URL + "/slow.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, created.Status)
+	assert.Empty(t, created.Files)
+
+	close(release)
+
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Equal(t, []string{"slow.pdf"}, archive.Files)
+}
+
+func newConcurrencyServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var inFlight, maxInFlight atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			prev := maxInFlight.Load()
+			if cur <= prev || maxInFlight.CompareAndSwap(prev, cur) {
+				break
+			}
+		}
+
+		switch r.URL.Path {
+		case "/a.pdf":
+			time.Sleep(150 * time.Millisecond)
+		case "/b.pdf":
+			time.Sleep(100 * time.Millisecond)
+		case "/missing.pdf":
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &maxInFlight
+}
+
+func TestArchiveService_CreateArchive_ParallelDownloads(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ts, maxInFlight := newConcurrencyServer(t)
+
+	urls := []string{ts.URL + "/a.pdf", ts.URL + "/missing.pdf", ts.URL + "/b.pdf"}
+	created, err := service.CreateArchive(context.Background(), urls)
+	require.NoError(t, err)
+
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Equal(t, []string{"a.pdf", "b.pdf"}, archive.Files)
+	require.Len(t, archive.Errors, 1)
+	assert.True(t, strings.HasPrefix(archive.Errors[0], ts.URL+"/missing.pdf - "))
+	assert.Equal(t, int32(3), maxInFlight.Load())
+}
+
+func TestArchiveService_CreateArchive_DownloadLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		perArchive  int
+		global      int
+		expectedMax int32
+	}{
+		{"per archive", 1, 10, 1},
+		{"global", 3, 2, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, cleanup := setupTestService(t)
+			defer cleanup()
+
+			service.cfg.DownloadsPerArchive = test.perArchive
+			service.downloadSem = make(chan struct{}, test.global)
+
+			ts, maxInFlight := newConcurrencyServer(t)
+
+			urls := []string{ts.URL + "/a.pdf", ts.URL + "/b.pdf", ts.URL + "/c.pdf"}
+			created, err := service.CreateArchive(context.Background(), urls)
+			require.NoError(t, err)
+
+			archive := waitForArchive(t, service, created.ID)
+			assert.Equal(t, []string{"a.pdf", "b.pdf", "c.pdf"}, archive.Files)
+			assert.Equal(t, test.expectedMax, maxInFlight.Load())
+		})
+	}
+}
+
+func TestArchiveService_CreateArchive_AfterShutdown(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	require.NoError(t, service.Shutdown(context.Background()))
+
+	_, err := service.CreateArchive(context.Background(), []string{testPDFURL})
+	assert.ErrorIs(t, err, ErrServiceStopped)
+
+	count, err := service.repo.CountArchivesInProcess(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestArchiveService_CreateArchive_QueueFull(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// скачивания висят, пока тест не отпустит их: воркеры заняты, очередь заполняется
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	service.cfg.MaxArchivesInProcess = 100
+	capacity := service.cfg.WorkersCount + service.cfg.WorkerQueueSize
+
+	admitted := 0
+	var err error
+	for i := 0; i <= capacity; i++ {
+		if _, err = service.CreateArchive(context.Background(), []string{ts.URL + "/file.pdf"}); err != nil {
+			break
+		}
+		admitted++
+	}
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.LessOrEqual(t, admitted, capacity)
+
+	count, err := service.repo.CountArchivesInProcess(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, admitted, count)
+}
+
+func TestArchiveService_CreateArchive_LimitUnderLoad(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// сборки висят на скачивании, чтобы задачи оставались в работе до конца теста
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4 test"))
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx := context.Background()
+	const n = 64
+
+	var wg sync.WaitGroup
+	var admitted atomic.Int32
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if i%2 == 0 {
+				_, err = service.CreateEmptyArchive(ctx)
+			} else {
+				_, err = service.CreateArchive(ctx, []string{ts.URL + "/file.pdf"})
+			}
+			if err == nil {
+				admitted.Add(1)
+				return
+			}
+			assert.Equal(t, ErrServerBusy, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, service.cfg.MaxArchivesInProcess, admitted.Load())
+
+	count, err := service.repo.CountArchivesInProcess(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, service.cfg.MaxArchivesInProcess, count)
+}
+
+func TestArchiveService_ListArchives(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < maxListLimit+5; i++ {
+		require.NoError(t, service.repo.SaveArchive(ctx, &models.Archive{
+			ID:        fmt.Sprintf("a%03d", i),
+			Status:    models.ArchiveStatusReady,
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+			UpdatedAt: base,
+		}))
+	}
+
+	page, err := service.ListArchives(ctx, models.ArchiveQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Archives, defaultListLimit)
+	assert.Equal(t, "a000", page.Archives[0].ID)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = service.ListArchives(ctx, models.ArchiveQuery{Limit: maxListLimit * 10, Desc: true})
+	require.NoError(t, err)
+	assert.Len(t, page.Archives, maxListLimit)
+	assert.Equal(t, fmt.Sprintf("a%03d", maxListLimit+4), page.Archives[0].ID)
+
+	invalid := []models.ArchiveQuery{
+		{Statuses: []models.ArchiveStatus{"done"}},
+		{SortBy: "name"},
+		{Limit: -1},
+		{CreatedFrom: base, CreatedTo: base},
+		{Cursor: "???"},
+	}
+	for _, query := range invalid {
+		_, err := service.ListArchives(ctx, query)
+		assert.ErrorIs(t, err, ErrInvalidListQuery, "%+v", query)
+	}
+}
+
 func TestArchiveService_CreateArchive_AllFailed(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
@@ -105,9 +498,10 @@ func TestArchiveService_CreateArchive_AllFailed(t *testing.T) {
 	ctx := context.Background()
 	urls := []string{invalidURL, notFoundURL, testPNG}
 
-	archive, err := service.CreateArchive(ctx, urls)
-
+	created, err := service.CreateArchive(ctx, urls)
 	require.NoError(t, err)
+
+	archive := waitForArchive(t, service, created.ID)
 	assert.Equal(t, models.ArchiveStatusFailed, archive.Status)
 	assert.Empty(t, archive.Files)
 	assert.Len(t, archive.Errors, 3)
@@ -158,283 +552,1293 @@ func TestArchiveService_CreateEmptyArchive_Success(t *testing.T) {
 
 	ctx := context.Background()
 
-	archive, err := service.CreateEmptyArchive(ctx)
+	archive, err := service.CreateEmptyArchive(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusEmpty, archive.Status)
+	assert.Empty(t, archive.Files)
+	assert.Empty(t, archive.Errors)
+	assert.NotEmpty(t, archive.ID)
+}
+
+func TestArchiveService_AddFile_Success(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	require.NoError(t, err)
+
+	updatedArchive, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, updatedArchive.Status)
+	assert.Len(t, updatedArchive.Files, 1)
+}
+
+func TestArchiveService_AddFile_ArchiveBecomesReady(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testJPEGURL)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	require.NoError(t, err)
+
+	updatedArchive, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, updatedArchive.Status)
+	assert.Len(t, updatedArchive.Files, 3)
+}
+
+func TestArchiveService_AddFile_ArchiveNotFound(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	err := service.AddFile(ctx, "nonexistent-id", testPDFURL)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "не удалось получить архив")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+func TestArchiveService_AddFile_ArchiveReady(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive := &models.Archive{
+		ID:        "test-ready",
+		Status:    models.ArchiveStatusReady,
+		Files:     []string{"file1.pdf"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Errors:    []string{},
+	}
+	err := service.repo.SaveArchive(ctx, archive)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrArchiveReady, err)
+}
+
+func TestArchiveService_AddFile_ArchiveFailed(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive := &models.Archive{
+		ID:        "test-failed",
+		Status:    models.ArchiveStatusFailed,
+		Files:     []string{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Errors:    []string{"some error"},
+	}
+	err := service.repo.SaveArchive(ctx, archive)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrArchiveFailed, err)
+}
+
+func TestArchiveService_AddFile_ArchiveFull(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive := &models.Archive{
+		ID:        "test-full",
+		Status:    models.ArchiveStatusBuilding,
+		Files:     []string{"file1.pdf", "file2.jpg", "file3.pdf"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Errors:    []string{},
+	}
+	err := service.repo.SaveArchive(ctx, archive)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrArchiveFull, err)
+}
+
+func TestArchiveService_AddFile_ArchiveChangedDuringDownload(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	// пока файл скачивается, архив заполняет другая реплика
+	filled := []string{"file1.pdf", "file2.pdf", "file3.pdf"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, service.repo.SaveArchive(ctx, &models.Archive{
+			ID:        archive.ID,
+			Status:    models.ArchiveStatusBuilding,
+			Files:     filled,
+			CreatedAt: archive.CreatedAt,
+			UpdatedAt: time.Now(),
+		}))
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4 test"))
+	}))
+	defer ts.Close()
+
+	err = service.AddFile(ctx, archive.ID, ts.URL+"/late.pdf")
+	assert.ErrorIs(t, err, ErrArchiveFull)
+
+	got, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, filled, got.Files)
+	assert.NoFileExists(t, filepath.Join(service.cfg.TempDir, archive.ID, "late.pdf"))
+}
+
+func TestArchiveService_AddFile_Concurrent(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	const n = 6
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = service.AddFile(ctx, archive.ID, testPDFURL)
+		}()
+	}
+	wg.Wait()
+
+	added := 0
+	for _, err := range errs {
+		if err == nil {
+			added++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrArchiveFull) || errors.Is(err, ErrArchiveReady), err)
+	}
+	assert.Equal(t, service.cfg.MaxFilesPerArchive, added)
+
+	got, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, got.Status)
+	assert.Len(t, got.Files, service.cfg.MaxFilesPerArchive)
+}
+
+func TestArchiveService_QueuedJob_RejectsManualChanges(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// оба воркера заняты, задача со списком URL ждет в очереди
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	for range service.cfg.WorkersCount {
+		_, err := service.CreateArchive(ctx, []string{ts.URL + "/file.pdf"})
+		require.NoError(t, err)
+	}
+
+	queued, err := service.CreateArchive(ctx, []string{testPDFURL, testPDFURL, testPDFURL})
+	require.NoError(t, err)
+
+	for range service.cfg.MaxFilesPerArchive {
+		assert.ErrorIs(t, service.AddFile(ctx, queued.ID, testPDFURL), ErrArchiveJob)
+	}
+	assert.ErrorIs(t, service.RemoveFile(ctx, queued.ID, "dummy.pdf"), ErrRemoveFromJob)
+	_, err = service.Finalize(ctx, queued.ID)
+	assert.ErrorIs(t, err, ErrFinalizeJob)
+
+	close(release)
+
+	archive := waitForArchive(t, service, queued.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.ElementsMatch(t, []string{"dummy.pdf", "dummy (2).pdf", "dummy (3).pdf"}, archive.Files)
+}
+
+func TestArchiveService_processJob_ArchiveChanged(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive := &models.Archive{
+		ID:        "test-changed",
+		Status:    models.ArchiveStatusReady,
+		Files:     []string{"dummy.pdf"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Job:       true,
+	}
+	require.NoError(t, service.repo.SaveArchive(ctx, archive))
+
+	service.processJob(ctx, buildJob{archiveID: archive.ID, urls: []string{testPDFURL}})
+
+	stored, err := service.repo.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, archive.Files, stored.Files)
+	assert.NoDirExists(t, filepath.Join(service.cfg.TempDir, archive.ID))
+}
+
+func TestArchiveService_AddFile_InvalidURL(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, invalidURL)
+
+	assert.Error(t, err)
+	assert.Equal(t, ErrInvalidFileURL, err)
+}
+
+func TestArchiveService_DeleteArchive_Ready(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	created, err := service.CreateArchive(ctx, []string{testPDFURL})
+	require.NoError(t, err)
+	archive := waitForArchive(t, service, created.ID)
+	require.Equal(t, models.ArchiveStatusReady, archive.Status)
+
+	zipPath := filepath.Join(service.cfg.ArchivesDir, archive.ID+".zip")
+	require.FileExists(t, zipPath)
+
+	require.NoError(t, service.DeleteArchive(ctx, archive.ID))
+
+	assert.NoFileExists(t, zipPath)
+	_, err = service.GetArchive(ctx, archive.ID)
+	assert.Error(t, err)
+
+	err = service.DeleteArchive(ctx, archive.ID)
+	assert.ErrorIs(t, err, ErrArchiveGet)
+}
+
+func TestArchiveService_DeleteArchive_CancelsDownload(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	started := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+		w.(http.Flusher).Flush()
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	created, err := service.CreateArchive(ctx, []string{ts.URL + "/endless.pdf"})
+	require.NoError(t, err)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("скачивание не началось")
+	}
+
+	deleted := make(chan error, 1)
+	go func() { deleted <- service.DeleteArchive(ctx, created.ID) }()
+
+	select {
+	case err := <-deleted:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("удаление не прервало скачивание")
+	}
+
+	_, err = service.GetArchive(ctx, created.ID)
+	assert.Error(t, err)
+	assert.NoDirExists(t, filepath.Join(service.cfg.TempDir, created.ID))
+}
+
+// Работа, начатая после отмены, но взявшая блокировку архива раньше удаления, не должна
+// скачивать файлы удаляемого архива.
+func TestArchiveService_DeleteArchive_PendingBlocksNewWork(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.AddFile(ctx, archive.ID, testPDFURL))
+
+	_, release := service.inflight.cancel(archive.ID)
+
+	err = service.AddFile(ctx, archive.ID, testJPEGURL)
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+	_, err = service.Finalize(ctx, archive.ID)
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+
+	release()
+
+	require.NoError(t, service.AddFile(ctx, archive.ID, testJPEGURL))
+	updated, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Len(t, updated.Files, 2)
+}
+
+func TestArchiveService_RemoveFile_Success(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.AddFile(ctx, archive.ID, testPDFURL))
+	require.NoError(t, service.AddFile(ctx, archive.ID, testJPEGURL))
+
+	added, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	require.Len(t, added.Files, 2)
+	removed := added.Files[0]
+	updatedAt := added.UpdatedAt
+
+	require.NoError(t, service.RemoveFile(ctx, archive.ID, removed))
+
+	updated, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, updated.Files, removed)
+	assert.Len(t, updated.Files, 1)
+	assert.Equal(t, models.ArchiveStatusBuilding, updated.Status)
+	assert.True(t, updated.UpdatedAt.After(updatedAt))
+	assert.NoFileExists(t, filepath.Join(service.cfg.TempDir, archive.ID, removed))
+
+	require.NoError(t, service.RemoveFile(ctx, archive.ID, updated.Files[0]))
+
+	updated, err = service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Empty(t, updated.Files)
+	assert.Equal(t, models.ArchiveStatusEmpty, updated.Status)
+}
+
+func TestArchiveService_AddFile_Entries(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.Error(t, service.AddFile(ctx, archive.ID, notFoundURL))
+	require.NoError(t, service.AddFile(ctx, archive.ID, testJPEGURL))
+
+	added, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	require.Len(t, added.Entries, 1, "неудачное добавление не оставляет записи")
+	entry := added.Entries[0]
+	assert.Equal(t, testJPEGURL, entry.URL)
+	assert.Equal(t, added.Files[0], entry.Name)
+	assert.Equal(t, models.FileStatusOK, entry.Status)
+	assert.Equal(t, "image/jpeg", entry.MIME)
+	assert.EqualValues(t, len(testJPEGData), entry.Size)
+
+	require.NoError(t, service.RemoveFile(ctx, archive.ID, entry.Name))
+
+	removed, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Empty(t, removed.Entries)
+}
+
+func TestFileErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{ErrInvalidFileURL, models.CodeInvalidFileURL},
+		{fmt.Errorf("%w: %w", ErrHostNotAllowed, hostpolicy.ErrHostRejected), models.CodeHostNotAllowed},
+		{fmt.Errorf("%w: text/html", ErrUnsupportedFile), models.CodeUnsupportedFile},
+		{fmt.Errorf("%w: больше 10 байт", ErrFileTooLarge), models.CodeFileTooLarge},
+		{fmt.Errorf("%w: лимит 10 байт", ErrArchiveTooLarge), models.CodeArchiveTooLarge},
+		{fmt.Errorf("%w: %w", ErrFileCopyFailed, ErrFileTruncated), models.CodeDownloadTruncated},
+		{fmt.Errorf("%w: %w", ErrFileDownloadFailed, &httpStatusError{StatusCode: 404}), models.CodeDownloadFailed},
+		{fmt.Errorf("%w: %v", ErrContextDone, context.Canceled), models.CodeRequestCanceled},
+		{fmt.Errorf("%w: disk full", ErrFileCopyFailed), models.CodeFilesystemError},
+		{errors.New("неизвестно"), models.CodeInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			assert.Equal(t, test.expected, fileErrorCode(test.err))
+		})
+	}
+}
+
+func TestArchiveService_RemoveFile_Errors(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for status, want := range map[models.ArchiveStatus]error{
+		models.ArchiveStatusReady:  ErrRemoveFromReady,
+		models.ArchiveStatusFailed: ErrRemoveFromFailed,
+	} {
+		archive := &models.Archive{
+			ID:        "test-" + string(status),
+			Status:    status,
+			Files:     []string{"file1.pdf"},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		require.NoError(t, service.repo.SaveArchive(ctx, archive))
+
+		err := service.RemoveFile(ctx, archive.ID, "file1.pdf")
+		assert.ErrorIs(t, err, want)
+	}
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	err = service.RemoveFile(ctx, archive.ID, "missing.pdf")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	err = service.RemoveFile(ctx, "nonexistent-id", "file1.pdf")
+	assert.ErrorIs(t, err, ErrArchiveGet)
+}
+
+func TestArchiveService_Finalize_PartialArchive(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.AddFile(ctx, archive.ID, testPDFURL))
+
+	finalized, err := service.Finalize(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusReady, finalized.Status)
+	assert.Len(t, finalized.Files, 1)
+	assert.FileExists(t, filepath.Join(service.cfg.ArchivesDir, archive.ID+".zip"))
+
+	_, err = service.Finalize(ctx, archive.ID)
+	assert.ErrorIs(t, err, ErrFinalizeReady)
+}
+
+func TestArchiveService_Finalize_Errors(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	empty, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	_, err = service.Finalize(ctx, empty.ID)
+	assert.ErrorIs(t, err, ErrArchiveEmpty)
+
+	failed := &models.Archive{
+		ID:        "test-failed",
+		Status:    models.ArchiveStatusFailed,
+		Files:     []string{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	require.NoError(t, service.repo.SaveArchive(ctx, failed))
+	_, err = service.Finalize(ctx, failed.ID)
+	assert.ErrorIs(t, err, ErrFinalizeFailed)
+
+	_, err = service.Finalize(ctx, "nonexistent-id")
+	assert.ErrorIs(t, err, ErrArchiveGet)
+}
+
+func TestArchiveService_AddFile_IdleFinalize(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.cfg.ArchiveIdleFinalize = 50 * time.Millisecond
+
+	ctx := context.Background()
+
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+	require.NoError(t, service.AddFile(ctx, archive.ID, testPDFURL))
+	require.NoError(t, service.AddFile(ctx, archive.ID, testJPEGURL))
+
+	finalized := waitForArchive(t, service, archive.ID)
+	assert.Equal(t, models.ArchiveStatusReady, finalized.Status)
+	assert.Len(t, finalized.Files, 2)
+}
+
+func TestArchiveService_GetArchive_Success(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	originalArchive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	archive, err := service.GetArchive(ctx, originalArchive.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, originalArchive.ID, archive.ID)
+	assert.Equal(t, originalArchive.Status, archive.Status)
+}
+
+func TestArchiveService_GetArchive_NotFound(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := service.GetArchive(ctx, "nonexistent-id")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "не удалось получить архив")
+	assert.ErrorIs(t, err, infra.ErrArchiveNotFound)
+}
+
+func TestArchiveService_isValidURL(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://example.com/file.pdf", true},
+		{"http://example.com/file.jpg", true},
+		{"ftp://example.com/file.pdf", false},
+		{"invalid-url", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		result := service.isValidURL(test.url)
+		assert.Equal(t, test.expected, result, "URL: %s", test.url)
+	}
+}
+
+func TestArchiveService_isValidExt(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/pdf", true},
+		{"image/jpeg", true},
+		{"image/jpg", true},
+		{"image/png", false},
+		{"text/plain", false},
+		{"application/pdf; charset=utf-8", true},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		result := service.isValidExt(test.contentType)
+		assert.Equal(t, test.expected, result, "Content-Type: %s", test.contentType)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     []byte
+		expected string
+	}{
+		{"pdf", testPDFData, "application/pdf"},
+		{"jpeg", testJPEGData, "image/jpeg"},
+		{"png", testPNGData, "image/png"},
+		{"gif", []byte("GIF89a\x01\x00"), "image/gif"},
+		{"html", []byte("<!DOCTYPE html><html><body>404</body></html>"), "text/html"},
+		{"text", []byte("plain text"), "text/plain"},
+		{"empty", nil, "text/plain"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, sniffContentType(test.head))
+		})
+	}
+}
+
+func TestArchiveService_download_ContentCheckModes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/octet.pdf":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(testPDFData)
+		case "/fake.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("<!DOCTYPE html><html><body>Not Found</body></html>"))
+		case "/real.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(testJPEGData)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		mode    string
+		path    string
+		allowed bool
+	}{
+		{config.ContentCheckHeader, "/octet.pdf", false},
+		{config.ContentCheckHeader, "/fake.jpg", true},
+		{config.ContentCheckSniff, "/octet.pdf", true},
+		{config.ContentCheckSniff, "/fake.jpg", false},
+		{config.ContentCheckBoth, "/octet.pdf", false},
+		{config.ContentCheckBoth, "/fake.jpg", false},
+		{config.ContentCheckBoth, "/real.jpg", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode+test.path, func(t *testing.T) {
+			service, cleanup := setupTestService(t)
+			defer cleanup()
+			service.cfg.ContentCheckMode = test.mode
+
+			reader, _, err := service.download(context.Background(), ts.URL+test.path, nil, nil)
+			if !test.allowed {
+				assert.ErrorIs(t, err, ErrUnsupportedFile)
+				return
+			}
+			require.NoError(t, err)
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.NotEmpty(t, data)
+		})
+	}
+}
+
+func TestFileNameFromResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		disposition string
+		mimeType    string
+		expected    string
+	}{
+		{"plain", "https://example.com/docs/a.pdf", "", "application/pdf", "a.pdf"},
+		{"query string", "https://example.com/a.pdf?token=x", "", "application/pdf", "a.pdf"},
+		{"no extension", "https://example.com/download", "", "application/pdf", "download.pdf"},
+		{"root path", "https://example.com/", "", "image/jpeg", "file.jpg"},
+		{"wrong extension", "https://example.com/photo.php", "", "image/jpeg", "photo.php.jpg"},
+		{"jpeg alias", "https://example.com/photo.JPEG", "", "image/jpeg", "photo.JPEG"},
+		{"disposition", "https://example.com/download", `attachment; filename="report.pdf"`, "application/pdf", "report.pdf"},
+		{"disposition rfc5987", "https://example.com/download", `attachment; filename="fallback.pdf"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D0%B5%D1%82.pdf`, "application/pdf", "отчет.pdf"},
+		{"disposition traversal", "https://example.com/download", `attachment; filename="../../etc/passwd"`, "application/pdf", "passwd.pdf"},
+		{"unsafe characters", "https://example.com/a%3Cb%3E%7C.pdf", "", "application/pdf", "a_b__.pdf"},
+		{"dots only", "https://example.com/..", "", "application/pdf", "file.pdf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			if test.disposition != "" {
+				header.Set("Content-Disposition", test.disposition)
+			}
+			assert.Equal(t, test.expected, fileNameFromResponse(test.url, header, test.mimeType))
+		})
+	}
+}
+
+func TestFileNames_reserve(t *testing.T) {
+	names := newFileNames([]string{"a.pdf"})
+
+	assert.Equal(t, "a (2).pdf", names.reserve("a.pdf"))
+	assert.Equal(t, "A (3).pdf", names.reserve("A.pdf"))
+	assert.Equal(t, "b.jpg", names.reserve("b.jpg"))
+
+	names.release("a (2).pdf")
+	assert.Equal(t, "a (2).pdf", names.reserve("a.pdf"))
+}
+
+func TestArchiveService_CreateArchive_DuplicateNames(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	urls := []string{testPDFURL, testPDFURL + "?copy=1", testPDFURL + "?copy=2"}
+	created, err := service.CreateArchive(context.Background(), urls)
+	require.NoError(t, err)
+
+	archive := waitForArchive(t, service, created.ID)
+	require.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.ElementsMatch(t, []string{"dummy.pdf", "dummy (2).pdf", "dummy (3).pdf"}, archive.Files)
+
+	zr, err := zip.OpenReader(filepath.Join(service.cfg.ArchivesDir, archive.ID+".zip"))
+	require.NoError(t, err)
+	defer zr.Close()
+	assert.Len(t, zr.File, 3)
+}
+
+func TestArchiveService_download_Success(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	reader, filename, err := service.download(ctx, testPDFURL, nil, nil)
+
+	require.NoError(t, err)
+	assert.NotNil(t, reader)
+	assert.NotEmpty(t, filename)
+
+	reader.Close()
+}
+
+func TestArchiveService_download_InvalidURL(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, _, err := service.download(ctx, invalidURL, nil, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "не удалось загрузить файл")
+}
+
+func TestArchiveService_download_NotFound(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, _, err := service.download(ctx, notFoundURL, nil, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "не удалось загрузить файл")
+}
+
+func TestArchiveService_writeFile_Success(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	archiveID := "test-save"
+	filename := "test.txt"
+
+	testData := "test file content"
+	reader := io.NopCloser(bytes.NewReader([]byte(testData)))
+
+	_, err := service.writeFile(ctx, archiveID, filename, reader, nil)
+
+	require.NoError(t, err)
+
+	filePath := filepath.Join(service.cfg.TempDir, archiveID, filename)
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, testData, string(content))
+}
+
+func TestArchiveService_download_StreamsToDisk(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	reader, filename, err := service.download(ctx, ts.URL+"/big.pdf", nil, nil)
+	require.NoError(t, err)
+
+	_, err = service.writeFile(ctx, "test-stream", filename, reader, nil)
+	require.NoError(t, err)
+	reader.Close()
+
+	content, err := os.ReadFile(filepath.Join(service.cfg.TempDir, "test-stream", filename))
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
+
+func TestArchiveService_writeFile_TruncatedBody(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("PDFDATA"))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	archiveID := "test-truncated"
+
+	reader, filename, err := service.download(ctx, ts.URL+"/cut.pdf", nil, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	_, err = service.writeFile(ctx, archiveID, filename, reader, nil)
+	assert.ErrorIs(t, err, ErrFileTruncated)
+
+	entries, err := os.ReadDir(filepath.Join(service.cfg.TempDir, archiveID))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestArchiveService_download_ContentLengthTooLarge(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.cfg.MaxFileSize = 5
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+	}))
+	defer ts.Close()
+
+	_, _, err := service.download(context.Background(), ts.URL+"/big.pdf", nil, nil)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+}
+
+func TestArchiveService_writeFile_StreamTooLarge(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.cfg.MaxFileSize = 1024
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			w.Write(bytes.Repeat([]byte("x"), 512))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	archiveID := "test-too-large"
+
+	reader, filename, err := service.download(ctx, ts.URL+"/chunked.pdf", nil, nil)
+	require.NoError(t, err)
+	defer reader.Close()
 
+	_, err = service.writeFile(ctx, archiveID, filename, reader, nil)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	entries, err := os.ReadDir(filepath.Join(service.cfg.TempDir, archiveID))
 	require.NoError(t, err)
-	assert.Equal(t, models.ArchiveStatusEmpty, archive.Status)
-	assert.Empty(t, archive.Files)
-	assert.Empty(t, archive.Errors)
-	assert.NotEmpty(t, archive.ID)
+	assert.Empty(t, entries)
 }
 
-func TestArchiveService_AddFile_Success(t *testing.T) {
+func TestArchiveService_CreateArchive_ArchiveTooLarge(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
-
-	archive, err := service.CreateEmptyArchive(ctx)
-	require.NoError(t, err)
+	service.cfg.MaxArchiveSize = int64(len(testPDFData)*2 + 1)
 
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	urls := []string{testPDFURL, testPDFURL + "?n=2", testPDFURL + "?n=3"}
+	created, err := service.CreateArchive(context.Background(), urls)
 	require.NoError(t, err)
 
-	updatedArchive, err := service.GetArchive(ctx, archive.ID)
-	require.NoError(t, err)
-	assert.Equal(t, models.ArchiveStatusBuilding, updatedArchive.Status)
-	assert.Len(t, updatedArchive.Files, 1)
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Len(t, archive.Files, 2)
+	require.Len(t, archive.Errors, 1)
+	assert.Contains(t, archive.Errors[0], ErrArchiveTooLarge.Error())
 }
 
-func TestArchiveService_AddFile_ArchiveBecomesReady(t *testing.T) {
+func TestArchiveService_AddFile_ArchiveTooLarge(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	service.cfg.MaxArchiveSize = int64(len(testPDFData) + 1)
 
+	ctx := context.Background()
 	archive, err := service.CreateEmptyArchive(ctx)
 	require.NoError(t, err)
 
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
-	require.NoError(t, err)
+	require.NoError(t, service.AddFile(ctx, archive.ID, testPDFURL))
 
 	err = service.AddFile(ctx, archive.ID, testJPEGURL)
-	require.NoError(t, err)
-
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
-	require.NoError(t, err)
-
-	updatedArchive, err := service.GetArchive(ctx, archive.ID)
-	require.NoError(t, err)
-	assert.Equal(t, models.ArchiveStatusReady, updatedArchive.Status)
-	assert.Len(t, updatedArchive.Files, 3)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
 }
 
-func TestArchiveService_AddFile_ArchiveNotFound(t *testing.T) {
-	service, cleanup := setupTestService(t)
-	defer cleanup()
-
-	ctx := context.Background()
+func newFlakyServer(t *testing.T, failures int, failure func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
 
-	err := service.AddFile(ctx, "nonexistent-id", testPDFURL)
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= failures {
+			failure(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+	}))
+	t.Cleanup(ts.Close)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "не удалось получить архив")
+	return ts, &calls
 }
 
-func TestArchiveService_AddFile_ArchiveReady(t *testing.T) {
+func TestArchiveService_AddFile_RetriesTransientStatus(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	ts, calls := newFlakyServer(t, 2, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	fileURL := ts.URL + "/flaky.pdf"
 
-	archive := &models.Archive{
-		ID:        "test-ready",
-		Status:    models.ArchiveStatusReady,
-		Files:     []string{"file1.pdf"},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Errors:    []string{},
-	}
-	err := service.repo.SaveArchive(ctx, archive)
+	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
 	require.NoError(t, err)
 
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	require.NoError(t, service.AddFile(ctx, archive.ID, fileURL))
+	assert.Equal(t, int32(3), calls.Load())
 
-	assert.Error(t, err)
-	assert.Equal(t, ErrArchiveReady, err)
+	updated, err := service.GetArchive(ctx, archive.ID)
+	require.NoError(t, err)
+	assert.Len(t, updated.Files, 1)
+	assert.Equal(t, 3, updated.Attempts[fileURL])
 }
 
-func TestArchiveService_AddFile_ArchiveFailed(t *testing.T) {
+func TestArchiveService_CreateArchive_RetriesExhausted(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	ts, calls := newFlakyServer(t, 10, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	fileURL := ts.URL + "/down.pdf"
 
-	archive := &models.Archive{
-		ID:        "test-failed",
-		Status:    models.ArchiveStatusFailed,
-		Files:     []string{},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Errors:    []string{"some error"},
-	}
-	err := service.repo.SaveArchive(ctx, archive)
+	created, err := service.CreateArchive(context.Background(), []string{fileURL})
 	require.NoError(t, err)
 
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
-
-	assert.Error(t, err)
-	assert.Equal(t, ErrArchiveFailed, err)
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusFailed, archive.Status)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 3, archive.Attempts[fileURL])
+	require.Len(t, archive.Errors, 1)
+	assert.Contains(t, archive.Errors[0], "HTTP status 502")
 }
 
-func TestArchiveService_AddFile_ArchiveFull(t *testing.T) {
+func TestArchiveService_CreateArchive_RetriesTruncatedBody(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	ts, calls := newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		w.Write(testPDFData)
+	})
+	fileURL := ts.URL + "/cut.pdf"
 
-	archive := &models.Archive{
-		ID:        "test-full",
-		Status:    models.ArchiveStatusBuilding,
-		Files:     []string{"file1.pdf", "file2.jpg", "file3.pdf"},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Errors:    []string{},
-	}
-	err := service.repo.SaveArchive(ctx, archive)
+	created, err := service.CreateArchive(context.Background(), []string{fileURL})
 	require.NoError(t, err)
 
-	err = service.AddFile(ctx, archive.ID, testPDFURL)
-
-	assert.Error(t, err)
-	assert.Equal(t, ErrArchiveFull, err)
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, 2, archive.Attempts[fileURL])
 }
 
-func TestArchiveService_AddFile_InvalidURL(t *testing.T) {
+func TestArchiveService_AddFile_NoRetryOnPermanentError(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	ts, calls := newFlakyServer(t, 10, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+	})
 
+	ctx := context.Background()
 	archive, err := service.CreateEmptyArchive(ctx)
 	require.NoError(t, err)
 
-	err = service.AddFile(ctx, archive.ID, invalidURL)
-
-	assert.Error(t, err)
-	assert.Equal(t, ErrInvalidFileURL, err)
+	err = service.AddFile(ctx, archive.ID, ts.URL+"/missing.pdf")
+	assert.ErrorIs(t, err, ErrFileDownloadFailed)
+	assert.Equal(t, int32(1), calls.Load())
 }
 
-func TestArchiveService_GetArchive_Success(t *testing.T) {
+func TestArchiveService_AddFile_RetryAfterTooLong(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
+	ts, calls := newFlakyServer(t, 10, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
 
-	originalArchive, err := service.CreateEmptyArchive(ctx)
+	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
 	require.NoError(t, err)
 
-	archive, err := service.GetArchive(ctx, originalArchive.ID)
-
-	require.NoError(t, err)
-	assert.Equal(t, originalArchive.ID, archive.ID)
-	assert.Equal(t, originalArchive.Status, archive.Status)
+	err = service.AddFile(ctx, archive.ID, ts.URL+"/limited.pdf")
+	assert.ErrorIs(t, err, ErrFileDownloadFailed)
+	assert.Equal(t, int32(1), calls.Load())
 }
 
-func TestArchiveService_GetArchive_NotFound(t *testing.T) {
+func TestArchiveService_retryDelay(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
+	service.cfg.DownloadRetryBaseDelay = 100 * time.Millisecond
+	service.cfg.DownloadRetryMaxDelay = time.Second
+
 	ctx := context.Background()
+	unavailable := fmt.Errorf("%w: %w", ErrFileDownloadFailed, &httpStatusError{StatusCode: http.StatusServiceUnavailable})
 
-	_, err := service.GetArchive(ctx, "nonexistent-id")
+	for attempt, upper := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay, retry := service.retryDelay(ctx, attempt, unavailable)
+		assert.True(t, retry)
+		assert.GreaterOrEqual(t, delay, upper/2)
+		assert.LessOrEqual(t, delay, upper)
+	}
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "не удалось получить архив")
+	retryAfter := &httpStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 300 * time.Millisecond}
+	delay, retry := service.retryDelay(ctx, 1, retryAfter)
+	assert.True(t, retry)
+	assert.Equal(t, 300*time.Millisecond, delay)
+
+	_, retry = service.retryDelay(ctx, 1, &httpStatusError{StatusCode: http.StatusForbidden})
+	assert.False(t, retry)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, retry = service.retryDelay(canceled, 1, unavailable)
+	assert.False(t, retry)
 }
 
-func TestArchiveService_isValidURL(t *testing.T) {
-	service, cleanup := setupTestService(t)
-	defer cleanup()
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 8, 5, 12, 0, 0, 0, time.UTC)
 
 	tests := []struct {
-		url      string
-		expected bool
+		value string
+		want  time.Duration
+		ok    bool
 	}{
-		{"https://example.com/file.pdf", true},
-		{"http://example.com/file.jpg", true},
-		{"ftp://example.com/file.pdf", false},
-		{"invalid-url", false},
-		{"", false},
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Tue, 05 Aug 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 05 Aug 2025 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
 	}
 
-	for _, test := range tests {
-		result := service.isValidURL(test.url)
-		assert.Equal(t, test.expected, result, "URL: %s", test.url)
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		assert.Equal(t, tt.ok, ok, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
 	}
 }
 
-func TestArchiveService_isValidExt(t *testing.T) {
-	service, cleanup := setupTestService(t)
-	defer cleanup()
+// newResumableServer обрывает первый ответ на середине, а дальше отдает файл через
+// http.ServeContent, который сам обрабатывает Range и If-Range.
+func newResumableServer(t *testing.T, header func(call int32, h http.Header)) (*httptest.Server, []byte, *[]string) {
+	t.Helper()
+
+	data := append(append([]byte{}, testPDFData...), bytes.Repeat([]byte("x"), 4096)...)
+	var (
+		calls  atomic.Int32
+		mu     sync.Mutex
+		ranges []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
 
-	tests := []struct {
-		contentType string
-		expected    bool
-	}{
-		{"application/pdf", true},
-		{"image/jpeg", true},
-		{"image/jpg", true},
-		{"image/png", false},
-		{"text/plain", false},
-		{"application/pdf; charset=utf-8", true},
-		{"", false},
-	}
+		w.Header().Set("Content-Type", "application/pdf")
+		header(call, w.Header())
+		if call == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(data[:len(data)/2])
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(ts.Close)
 
-	for _, test := range tests {
-		result := service.isValidExt(test.contentType)
-		assert.Equal(t, test.expected, result, "Content-Type: %s", test.contentType)
-	}
+	return ts, data, &ranges
+}
+
+// assertEntryDescribes проверяет, что размер и SHA-256 в записи посчитаны по всему файлу,
+// а не только по докачанной части.
+func assertEntryDescribes(t *testing.T, service *archiveService, archiveID string, data []byte) {
+	t.Helper()
+
+	archive, err := service.GetArchive(context.Background(), archiveID)
+	require.NoError(t, err)
+	require.Len(t, archive.Entries, 1)
+
+	sum := sha256.Sum256(data)
+	entry := archive.Entries[0]
+	assert.EqualValues(t, len(data), entry.Size)
+	assert.Equal(t, "application/pdf", entry.MIME)
+	assert.Equal(t, hex.EncodeToString(sum[:]), entry.SHA256)
 }
 
-func TestArchiveService_downloadFile_Success(t *testing.T) {
+func TestArchiveService_AddFile_ResumesWithRange(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
+	ts, data, ranges := newResumableServer(t, func(_ int32, h http.Header) {
+		h.Set("Accept-Ranges", "bytes")
+		h.Set("ETag", `"v1"`)
+	})
+
 	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
 
-	reader, filename, err := service.downloadFile(ctx, testPDFURL)
+	require.NoError(t, service.AddFile(ctx, archive.ID, ts.URL+"/big.pdf"))
+	assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(data)/2)}, *ranges)
 
+	got, err := os.ReadFile(filepath.Join(service.cfg.TempDir, archive.ID, "big.pdf"))
 	require.NoError(t, err)
-	assert.NotNil(t, reader)
-	assert.NotEmpty(t, filename)
-
-	reader.Close()
+	assert.Equal(t, data, got)
+	assertEntryDescribes(t, service, archive.ID, data)
 }
 
-func TestArchiveService_downloadFile_InvalidURL(t *testing.T) {
+func TestArchiveService_AddFile_ResumeValidatorChanged(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
+	ts, data, ranges := newResumableServer(t, func(call int32, h http.Header) {
+		h.Set("Accept-Ranges", "bytes")
+		h.Set("ETag", fmt.Sprintf(`"v%d"`, call))
+	})
+
 	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
 
-	_, _, err := service.downloadFile(ctx, invalidURL)
+	require.NoError(t, service.AddFile(ctx, archive.ID, ts.URL+"/big.pdf"))
+	require.Len(t, *ranges, 2)
+	assert.NotEmpty(t, (*ranges)[1])
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "не удалось загрузить файл")
+	got, err := os.ReadFile(filepath.Join(service.cfg.TempDir, archive.ID, "big.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	assertEntryDescribes(t, service, archive.ID, data)
 }
 
-func TestArchiveService_downloadFile_NotFound(t *testing.T) {
+// Докачка живет в пределах одного вызова: после исчерпанных попыток .part не остается.
+func TestArchiveService_AddFile_DiscardsPartAfterLastAttempt(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
+	data := append(append([]byte{}, testPDFData...), bytes.Repeat([]byte("x"), 4096)...)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data[:len(data)/2])
+	}))
+	defer ts.Close()
+
 	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
 
-	_, _, err := service.downloadFile(ctx, notFoundURL)
+	err = service.AddFile(ctx, archive.ID, ts.URL+"/big.pdf")
+	assert.ErrorIs(t, err, ErrFileTruncated)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "не удалось загрузить файл")
+	parts, _ := filepath.Glob(filepath.Join(service.cfg.TempDir, archive.ID, "*"+partSuffix))
+	assert.Empty(t, parts)
 }
 
-func TestArchiveService_saveFile_Success(t *testing.T) {
+func TestArchiveService_CreateArchive_NoResumeWithoutAcceptRanges(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
-	ctx := context.Background()
-	archiveID := "test-save"
-	filename := "test.txt"
+	ts, _, ranges := newResumableServer(t, func(_ int32, h http.Header) {
+		h.Set("ETag", `"v1"`)
+	})
 
-	testData := "test file content"
-	reader := io.NopCloser(bytes.NewReader([]byte(testData)))
+	created, err := service.CreateArchive(context.Background(), []string{ts.URL + "/big.pdf"})
+	require.NoError(t, err)
 
-	err := service.saveFile(ctx, archiveID, filename, reader)
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Equal(t, []string{"", ""}, *ranges)
+}
 
-	require.NoError(t, err)
+func TestResumeValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"no accept-ranges", http.Header{"Etag": {`"a"`}}, ""},
+		{"strong etag", http.Header{"Accept-Ranges": {"bytes"}, "Etag": {`"a"`}}, `"a"`},
+		{"weak etag falls back", http.Header{"Accept-Ranges": {"bytes"}, "Etag": {`W/"a"`}, "Last-Modified": {"Tue, 05 Aug 2025 12:00:00 GMT"}}, "Tue, 05 Aug 2025 12:00:00 GMT"},
+		{"ranges none", http.Header{"Accept-Ranges": {"none"}, "Etag": {`"a"`}}, ""},
+	}
 
-	filePath := filepath.Join(service.cfg.TempDir, archiveID, filename)
-	content, err := os.ReadFile(filePath)
-	require.NoError(t, err)
-	assert.Equal(t, testData, string(content))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resumeValidator(tt.header))
+		})
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	start, ok := contentRangeStart("bytes 100-199/200")
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), start)
+
+	_, ok = contentRangeStart("bytes */200")
+	assert.False(t, ok)
+
+	_, ok = contentRangeStart("items 1-2/3")
+	assert.False(t, ok)
 }
 
 func TestArchiveService_buildZip_Success(t *testing.T) {
@@ -710,9 +2114,11 @@ func TestArchiveService_CreateArchive_WithJPEG(t *testing.T) {
 	ctx := context.Background()
 	urls := []string{testJPEGURL}
 
-	archive, err := service.CreateArchive(ctx, urls)
-
+	created, err := service.CreateArchive(ctx, urls)
 	require.NoError(t, err)
+	assert.Equal(t, models.ArchiveStatusBuilding, created.Status)
+
+	archive := waitForArchive(t, service, created.ID)
 	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
 	assert.Len(t, archive.Files, 1)
 	assert.Empty(t, archive.Errors)
@@ -809,7 +2215,71 @@ func TestArchiveService_AddFile_BuildZipFail(t *testing.T) {
 	require.NotEmpty(t, updated.Errors)
 }
 
-func TestArchiveService_downloadFile_UnsupportedContentType(t *testing.T) {
+func TestArchiveService_download_ForbiddenAddress(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.httpClient = netguard.NewClient(5*time.Second, netguard.New(nil, nil))
+
+	_, _, err := service.download(context.Background(), testPDFURL, nil, nil)
+	assert.ErrorIs(t, err, ErrFileDownloadFailed)
+	assert.ErrorIs(t, err, netguard.ErrForbiddenAddress)
+}
+
+func TestArchiveService_AddFile_HostDenied(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.hosts = hostpolicy.New(nil, []hostpolicy.Pattern{hostpolicy.MustParse("127.0.0.1")})
+
+	ctx := context.Background()
+	archive, err := service.CreateEmptyArchive(ctx)
+	require.NoError(t, err)
+
+	err = service.AddFile(ctx, archive.ID, testPDFURL)
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+
+	var rejected *hostpolicy.RejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, "127.0.0.1", rejected.Host)
+	assert.Equal(t, "127.0.0.1", rejected.Rule)
+}
+
+func TestArchiveService_CreateArchive_HostNotInAllowList(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.hosts = hostpolicy.New([]hostpolicy.Pattern{hostpolicy.MustParse("localhost")}, nil)
+
+	localhostPDF := strings.Replace(testPDFURL, "127.0.0.1", "localhost", 1)
+	urls := []string{testPDFURL, localhostPDF}
+
+	created, err := service.CreateArchive(context.Background(), urls)
+	require.NoError(t, err)
+
+	archive := waitForArchive(t, service, created.ID)
+	assert.Equal(t, models.ArchiveStatusReady, archive.Status)
+	assert.Len(t, archive.Files, 1)
+	require.Len(t, archive.Errors, 1)
+	assert.True(t, strings.HasPrefix(archive.Errors[0], testPDFURL+" - "+ErrHostNotAllowed.Error()))
+}
+
+func TestArchiveService_download_RedirectToDeniedHost(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.hosts = hostpolicy.New(nil, []hostpolicy.Pattern{hostpolicy.MustParse("localhost")})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(testPDFURL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer ts.Close()
+
+	_, _, err := service.download(context.Background(), ts.URL+"/redirect.pdf", nil, nil)
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+}
+
+func TestArchiveService_download_UnsupportedContentType(t *testing.T) {
 	service, cleanup := setupTestService(t)
 	defer cleanup()
 
@@ -822,7 +2292,7 @@ func TestArchiveService_downloadFile_UnsupportedContentType(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	_, _, err := service.downloadFile(ctx, ts.URL+"/x.png")
+	_, _, err := service.download(ctx, ts.URL+"/x.png", nil, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "неподдерживаемый файл")
 }
@@ -844,11 +2314,11 @@ func TestArchiveService_CreateArchive_SaveError(t *testing.T) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempFilesDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
 	}
 
 	mockRepo := new(mocks.Database)
-	mockRepo.On("CountArchivesInProcess", mock.Anything).Return(0, nil).Once()
-	mockRepo.On("SaveArchive", mock.Anything, mock.Anything).Return(assert.AnError).Once()
+	mockRepo.On("InsertArchive", mock.Anything, mock.Anything, cfg.MaxArchivesInProcess).Return(assert.AnError).Once()
 
 	svc := New(logger, cfg, mockRepo).(*archiveService)
 
@@ -876,6 +2346,7 @@ func TestArchiveService_AddFile_SaveError(t *testing.T) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempFilesDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
 	}
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -895,7 +2366,7 @@ func TestArchiveService_AddFile_SaveError(t *testing.T) {
 		Errors:    []string{},
 	}
 	mockRepo.On("GetArchive", mock.Anything, arch.ID).Return(arch, nil).Once()
-	mockRepo.On("SaveArchive", mock.Anything, mock.AnythingOfType("*models.Archive")).Return(assert.AnError).Once()
+	mockRepo.On("UpdateArchive", mock.Anything, arch.ID, mock.Anything).Return(nil, assert.AnError).Once()
 
 	svc := New(logger, cfg, mockRepo).(*archiveService)
 
@@ -903,13 +2374,17 @@ func TestArchiveService_AddFile_SaveError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "не удалось сохранить архив")
 
+	// файл, не попавший в запись, не остается на диске
+	entries, _ := os.ReadDir(filepath.Join(tempFilesDir, arch.ID))
+	assert.Empty(t, entries)
+
 	os.RemoveAll(tempDir)
 }
 
-func TestArchiveService_CreateArchive_CountInProcessError(t *testing.T) {
+func TestArchiveService_CreateArchive_LimitReached(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 
-	tempDir := filepath.Join(os.TempDir(), "svc_cnt_err_"+time.Now().Format("20060102150405"))
+	tempDir := filepath.Join(os.TempDir(), "svc_limit_"+time.Now().Format("20060102150405"))
 	archivesDir := filepath.Join(tempDir, "archives")
 	tempFilesDir := filepath.Join(tempDir, "temp")
 	require.NoError(t, os.MkdirAll(archivesDir, 0755))
@@ -923,16 +2398,16 @@ func TestArchiveService_CreateArchive_CountInProcessError(t *testing.T) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempFilesDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
 	}
 
 	mockRepo := new(mocks.Database)
-	mockRepo.On("CountArchivesInProcess", mock.Anything).Return(0, assert.AnError).Once()
+	mockRepo.On("InsertArchive", mock.Anything, mock.Anything, cfg.MaxArchivesInProcess).Return(infra.ErrLimitReached).Once()
 
 	svc := New(logger, cfg, mockRepo).(*archiveService)
 
 	_, err := svc.CreateArchive(context.Background(), []string{testPDFURL})
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "не удалось получить количество архивов")
+	assert.Equal(t, ErrServerBusy, err)
 
 	os.RemoveAll(tempDir)
 }
@@ -954,11 +2429,11 @@ func TestArchiveService_CreateEmptyArchive_SaveError(t *testing.T) {
 		ArchiveTTL:           1 * time.Hour,
 		ArchivesDir:          archivesDir,
 		TempDir:              tempFilesDir,
+		SSRFAllowCIDRs:       []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
 	}
 
 	mockRepo := new(mocks.Database)
-	mockRepo.On("CountArchivesInProcess", mock.Anything).Return(0, nil).Once()
-	mockRepo.On("SaveArchive", mock.Anything, mock.AnythingOfType("*models.Archive")).Return(assert.AnError).Once()
+	mockRepo.On("InsertArchive", mock.Anything, mock.AnythingOfType("*models.Archive"), cfg.MaxArchivesInProcess).Return(assert.AnError).Once()
 
 	svc := New(logger, cfg, mockRepo).(*archiveService)
 
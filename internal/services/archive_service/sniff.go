@@ -0,0 +1,50 @@
+package archive_service
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/sunr3d/05-08-2025/internal/config"
+)
+
+// sniffLen — сколько первых байт тела читается для определения типа файла.
+const sniffLen = 512
+
+var magicNumbers = []struct {
+	prefix   []byte
+	mimeType string
+}{
+	{[]byte("%PDF-"), "application/pdf"},
+	{[]byte{0xFF, 0xD8, 0xFF}, "image/jpeg"},
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte("GIF87a"), "image/gif"},
+	{[]byte("GIF89a"), "image/gif"},
+	{[]byte("PK\x03\x04"), "application/zip"},
+}
+
+func sniffContentType(head []byte) string {
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(head, m.prefix) {
+			return m.mimeType
+		}
+	}
+
+	return mediaType(http.DetectContentType(head))
+}
+
+func mediaType(contentType string) string {
+	contentType = strings.Split(contentType, ";")[0]
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+func (s *archiveService) isAllowedContent(headerType, sniffedType string) bool {
+	switch s.cfg.ContentCheckMode {
+	case config.ContentCheckSniff:
+		return s.isValidExt(sniffedType)
+	case config.ContentCheckBoth:
+		return s.isValidExt(headerType) && s.isValidExt(sniffedType)
+	default:
+		return s.isValidExt(headerType)
+	}
+}
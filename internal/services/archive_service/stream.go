@@ -0,0 +1,105 @@
+package archive_service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// sizeBudget ограничивает суммарный объем файлов одного архива.
+// Нулевой лимит означает отсутствие ограничения.
+type sizeBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+func newSizeBudget(limit, used int64) *sizeBudget {
+	b := &sizeBudget{limit: limit}
+	b.used.Store(used)
+	return b
+}
+
+func (b *sizeBudget) fits(n int64) bool {
+	return b == nil || b.limit <= 0 || b.used.Load()+n <= b.limit
+}
+
+func (b *sizeBudget) reserve(n int64) bool {
+	if b == nil || b.limit <= 0 {
+		return true
+	}
+	if b.used.Add(n) > b.limit {
+		b.used.Add(-n)
+		return false
+	}
+	return true
+}
+
+func (b *sizeBudget) release(n int64) {
+	if b == nil || b.limit <= 0 {
+		return
+	}
+	b.used.Add(-n)
+}
+
+// streamReader отдает тело ответа по мере чтения, следит за лимитами размера
+// и проверяет, что получено ровно столько байт, сколько обещал Content-Length.
+type streamReader struct {
+	body     io.ReadCloser
+	expected int64
+	limit    int64
+	budget   *sizeBudget
+	read     int64
+	complete bool
+	// validator — ETag или Last-Modified, по которому можно продолжить скачивание.
+	validator string
+}
+
+func newStreamReader(body io.ReadCloser, contentLength, limit int64, budget *sizeBudget) *streamReader {
+	return &streamReader{
+		body:     body,
+		expected: contentLength,
+		limit:    limit,
+		budget:   budget,
+	}
+}
+
+// resumeAt учитывает байты, уже лежащие на диске: лимиты и проверка длины считаются от начала файла.
+func (r *streamReader) resumeAt(offset int64) {
+	r.read = offset
+	if r.expected >= 0 {
+		r.expected += offset
+	}
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+
+	if r.limit > 0 && r.read+int64(n) > r.limit {
+		return n, fmt.Errorf("%w: больше %d байт", ErrFileTooLarge, r.limit)
+	}
+	if !r.budget.reserve(int64(n)) {
+		return n, fmt.Errorf("%w: лимит %d байт", ErrArchiveTooLarge, r.budget.limit)
+	}
+	r.read += int64(n)
+
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return n, fmt.Errorf("%w: получено %d байт", ErrFileTruncated, r.read)
+	case err == io.EOF && r.expected >= 0 && r.read != r.expected:
+		return n, fmt.Errorf("%w: получено %d из %d байт", ErrFileTruncated, r.read, r.expected)
+	case err == io.EOF:
+		r.complete = true
+	}
+
+	return n, err
+}
+
+// Close возвращает в бюджет архива байты недокачанного файла.
+func (r *streamReader) Close() error {
+	if !r.complete {
+		r.budget.release(r.read)
+		r.read = 0
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,279 @@
+package archive_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunr3d/05-08-2025/models"
+)
+
+// errJobArchiveChanged — архив задачи уже не ждет результатов воркера.
+var errJobArchiveChanged = errors.New("архив задачи изменен вне воркера")
+
+type buildJob struct {
+	archiveID string
+	urls      []string
+}
+
+func (s *archiveService) startWorkers(n int) {
+	for i := 0; i < n; i++ {
+		s.wg.Add(1)
+		go s.worker(i)
+	}
+}
+
+func (s *archiveService) worker(id int) {
+	defer s.wg.Done()
+
+	for job := range s.jobs {
+		s.logger.Debug("воркер взял задачу",
+			zap.Int("worker_id", id),
+			zap.String("archive_id", job.archiveID),
+		)
+		s.processJob(s.baseCtx, job)
+	}
+}
+
+func (s *archiveService) enqueue(job buildJob) error {
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+
+	if s.stopped {
+		return ErrServiceStopped
+	}
+
+	select {
+	case s.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (s *archiveService) processJob(ctx context.Context, job buildJob) {
+	ctx, done := s.inflight.track(ctx, job.archiveID)
+	defer done()
+
+	unlock := s.locks.lock(job.archiveID)
+	defer unlock()
+
+	if s.inflight.deleted(job.archiveID) {
+		s.logger.Info("архив удален до начала сборки", zap.String("archive_id", job.archiveID))
+		return
+	}
+
+	archive, err := s.repo.GetArchive(ctx, job.archiveID)
+	if err != nil {
+		s.logger.Info("архив удален до начала сборки",
+			zap.String("archive_id", job.archiveID),
+			zap.Error(err),
+		)
+		return
+	}
+	if err := checkJobArchive(archive); err != nil {
+		s.logger.Error("архив задачи нельзя собрать",
+			zap.String("archive_id", job.archiveID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	files, errs, attempts, entries := s.downloadFiles(ctx, job.archiveID, job.urls)
+	if ctx.Err() != nil && s.baseCtx.Err() == nil {
+		// отменена только эта сборка: архив удаляется, сохранять нечего
+		s.logger.Info("сборка архива отменена",
+			zap.String("archive_id", job.archiveID),
+			zap.Error(ctx.Err()),
+		)
+		return
+	}
+
+	// имена файлов выбраны без учета чужих: архив должен быть все еще пустым
+	archive, err = s.repo.UpdateArchive(ctx, job.archiveID, func(a *models.Archive) error {
+		if err := checkJobArchive(a); err != nil {
+			return err
+		}
+		a.Files = append(a.Files, files...)
+		a.Errors = append(a.Errors, errs...)
+		if a.Attempts == nil {
+			a.Attempts = make(map[string]int, len(attempts))
+		}
+		for url, n := range attempts {
+			a.Attempts[url] += n
+		}
+		// записи создаются вместе с архивом в порядке job.urls
+		for i, entry := range entries {
+			if i < len(a.Entries) && a.Entries[i].URL == entry.URL {
+				a.Entries[i] = entry
+			}
+		}
+		a.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("не удалось сохранить скачанные файлы архива",
+			zap.String("archive_id", job.archiveID),
+			zap.Error(err),
+		)
+		if err := s.cleanupTemp(context.Background(), job.archiveID); err != nil {
+			s.logger.Error("не удалось очистить временные файлы",
+				zap.String("archive_id", job.archiveID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	s.buildArchive(ctx, archive)
+	if _, err := s.commitBuild(ctx, archive); err != nil {
+		s.logger.Error("не удалось сохранить архив после сборки",
+			zap.String("archive_id", archive.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if len(archive.Files) > 0 {
+		s.logger.Info("архив собран",
+			zap.String("archive_id", archive.ID),
+			zap.String("status", string(archive.Status)),
+			zap.Int("total_urls", len(job.urls)),
+			zap.Int("successful_files", len(archive.Files)),
+			zap.Int("errors", len(archive.Errors)),
+		)
+	} else {
+		s.logger.Info("архив не создан, нет доступных файлов",
+			zap.String("archive_id", archive.ID),
+			zap.String("status", string(archive.Status)),
+			zap.Int("total_urls", len(job.urls)),
+			zap.Int("successful_files", len(archive.Files)),
+			zap.Int("errors", len(archive.Errors)),
+		)
+	}
+}
+
+// checkJobArchive проверяет, что архив принадлежит задаче и в него еще не записаны файлы.
+func checkJobArchive(a *models.Archive) error {
+	if !a.Job || a.Status != models.ArchiveStatusBuilding || len(a.Files) > 0 {
+		return fmt.Errorf("%w: статус %s, файлов %d", errJobArchiveChanged, a.Status, len(a.Files))
+	}
+	return nil
+}
+
+type downloadResult struct {
+	file     fetchedFile
+	attempts int
+	err      error
+	started  time.Time
+	finished time.Time
+}
+
+func (s *archiveService) downloadFiles(ctx context.Context, archiveID string, urls []string) ([]string, []string, map[string]int, []models.FileEntry) {
+	results := make([]downloadResult, len(urls))
+	archiveSem := make(chan struct{}, max(s.cfg.DownloadsPerArchive, 1))
+	budget := newSizeBudget(s.cfg.MaxArchiveSize, 0)
+	names := newFileNames(nil)
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			releaseArchive, err := acquire(ctx, archiveSem)
+			if err != nil {
+				results[i] = downloadResult{err: err, finished: time.Now()}
+				return
+			}
+			defer releaseArchive()
+
+			releaseGlobal, err := acquire(ctx, s.downloadSem)
+			if err != nil {
+				results[i] = downloadResult{err: err, finished: time.Now()}
+				return
+			}
+			defer releaseGlobal()
+
+			started := time.Now()
+			s.markDownloading(ctx, archiveID, i, url, started)
+			file, attempts, err := s.fetchFile(ctx, archiveID, url, budget, names)
+			results[i] = downloadResult{file: file, attempts: attempts, err: err, started: started, finished: time.Now()}
+		}()
+	}
+	wg.Wait()
+
+	files := make([]string, 0, len(urls))
+	errs := make([]string, 0, len(urls))
+	attempts := make(map[string]int, len(urls))
+	entries := pendingEntries(urls)
+	for i, res := range results {
+		entries[i].Attempts = res.attempts
+		entries[i].StartedAt = res.started
+		entries[i].FinishedAt = res.finished
+		if res.attempts > 0 {
+			attempts[urls[i]] += res.attempts
+		}
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s - %s", urls[i], res.err.Error()))
+			entries[i] = failedEntry(entries[i], res.err)
+			continue
+		}
+		files = append(files, res.file.name)
+		entries[i] = okEntry(entries[i], res.file)
+	}
+
+	return files, errs, attempts, entries
+}
+
+func (s *archiveService) fetchFile(ctx context.Context, archiveID, url string, budget *sizeBudget, names *fileNames) (fetchedFile, int, error) {
+	if !s.isValidURL(url) {
+		return fetchedFile{}, 0, ErrInvalidFileURL
+	}
+
+	if err := s.checkHost(url); err != nil {
+		return fetchedFile{}, 0, err
+	}
+
+	return s.fetch(ctx, archiveID, url, budget, names)
+}
+
+func acquire(ctx context.Context, sem chan struct{}) (func(), error) {
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	}
+}
+
+func (s *archiveService) Shutdown(ctx context.Context) error {
+	s.stopIdleTimers()
+
+	s.jobsMu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.jobs)
+	}
+	s.jobsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		s.logger.Info("воркеры сборки архивов остановлены")
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("%w: %v", ErrContextDone, ctx.Err())
+	}
+}
@@ -92,6 +92,54 @@ func (_m *ArchiveService) CreateEmptyArchive(ctx context.Context) (*models.Archi
 	return r0, r1
 }
 
+// DeleteArchive provides a mock function with given fields: ctx, archiveID
+func (_m *ArchiveService) DeleteArchive(ctx context.Context, archiveID string) error {
+	ret := _m.Called(ctx, archiveID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteArchive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, archiveID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Finalize provides a mock function with given fields: ctx, archiveID
+func (_m *ArchiveService) Finalize(ctx context.Context, archiveID string) (*models.Archive, error) {
+	ret := _m.Called(ctx, archiveID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Finalize")
+	}
+
+	var r0 *models.Archive
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Archive, error)); ok {
+		return rf(ctx, archiveID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Archive); ok {
+		r0 = rf(ctx, archiveID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Archive)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, archiveID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetArchive provides a mock function with given fields: ctx, archiveID
 func (_m *ArchiveService) GetArchive(ctx context.Context, archiveID string) (*models.Archive, error) {
 	ret := _m.Called(ctx, archiveID)
@@ -122,6 +170,72 @@ func (_m *ArchiveService) GetArchive(ctx context.Context, archiveID string) (*mo
 	return r0, r1
 }
 
+// ListArchives provides a mock function with given fields: ctx, query
+func (_m *ArchiveService) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListArchives")
+	}
+
+	var r0 *models.ArchivePage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ArchiveQuery) (*models.ArchivePage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.ArchiveQuery) *models.ArchivePage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ArchivePage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.ArchiveQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveFile provides a mock function with given fields: ctx, archiveID, filename
+func (_m *ArchiveService) RemoveFile(ctx context.Context, archiveID string, filename string) error {
+	ret := _m.Called(ctx, archiveID, filename)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveFile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, archiveID, filename)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Shutdown provides a mock function with given fields: ctx
+func (_m *ArchiveService) Shutdown(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Shutdown")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewArchiveService creates a new instance of ArchiveService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewArchiveService(t interface {
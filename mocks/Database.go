@@ -8,6 +8,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	models "github.com/sunr3d/05-08-2025/models"
+
+	time "time"
 )
 
 // Database is an autogenerated mock type for the Database type
@@ -91,6 +93,84 @@ func (_m *Database) GetArchive(ctx context.Context, id string) (*models.Archive,
 	return r0, r1
 }
 
+// InsertArchive provides a mock function with given fields: ctx, archive, maxInProcess
+func (_m *Database) InsertArchive(ctx context.Context, archive *models.Archive, maxInProcess int) error {
+	ret := _m.Called(ctx, archive, maxInProcess)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertArchive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Archive, int) error); ok {
+		r0 = rf(ctx, archive, maxInProcess)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListArchives provides a mock function with given fields: ctx, query
+func (_m *Database) ListArchives(ctx context.Context, query models.ArchiveQuery) (*models.ArchivePage, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListArchives")
+	}
+
+	var r0 *models.ArchivePage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ArchiveQuery) (*models.ArchivePage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.ArchiveQuery) *models.ArchivePage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ArchivePage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.ArchiveQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListExpired provides a mock function with given fields: ctx, retention
+func (_m *Database) ListExpired(ctx context.Context, retention map[models.ArchiveStatus]time.Duration) ([]string, error) {
+	ret := _m.Called(ctx, retention)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListExpired")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, map[models.ArchiveStatus]time.Duration) ([]string, error)); ok {
+		return rf(ctx, retention)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, map[models.ArchiveStatus]time.Duration) []string); ok {
+		r0 = rf(ctx, retention)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, map[models.ArchiveStatus]time.Duration) error); ok {
+		r1 = rf(ctx, retention)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveArchive provides a mock function with given fields: ctx, archive
 func (_m *Database) SaveArchive(ctx context.Context, archive *models.Archive) error {
 	ret := _m.Called(ctx, archive)
@@ -109,6 +189,36 @@ func (_m *Database) SaveArchive(ctx context.Context, archive *models.Archive) er
 	return r0
 }
 
+// UpdateArchive provides a mock function with given fields: ctx, id, fn
+func (_m *Database) UpdateArchive(ctx context.Context, id string, fn func(*models.Archive) error) (*models.Archive, error) {
+	ret := _m.Called(ctx, id, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateArchive")
+	}
+
+	var r0 *models.Archive
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(*models.Archive) error) (*models.Archive, error)); ok {
+		return rf(ctx, id, fn)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(*models.Archive) error) *models.Archive); ok {
+		r0 = rf(ctx, id, fn)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Archive)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, func(*models.Archive) error) error); ok {
+		r1 = rf(ctx, id, fn)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewDatabase creates a new instance of Database. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDatabase(t interface {
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"maps"
+	"slices"
+	"time"
+)
 
 type ArchiveStatus string
 
@@ -11,6 +15,32 @@ const (
 	ArchiveStatusFailed   ArchiveStatus = "failed"
 )
 
+type FileStatus string
+
+const (
+	FileStatusPending     FileStatus = "pending"
+	FileStatusDownloading FileStatus = "downloading"
+	FileStatusOK          FileStatus = "ok"
+	FileStatusFailed      FileStatus = "failed"
+)
+
+// FileEntry — результат по одному URL задачи.
+type FileEntry struct {
+	URL string `json:"url"`
+	// Name — имя файла во временной директории и в zip, пусто до успешного скачивания.
+	Name   string     `json:"name,omitempty"`
+	Size   int64      `json:"size,omitempty"`
+	MIME   string     `json:"mime,omitempty"`
+	SHA256 string     `json:"sha256,omitempty"`
+	Status FileStatus `json:"status"`
+	// ErrorCode — стабильный код ошибки, тот же, что в ответах API; Error — ее текст.
+	ErrorCode  string    `json:"error_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
 type Archive struct {
 	ID        string        `json:"id"`
 	Status    ArchiveStatus `json:"status"`
@@ -18,4 +48,26 @@ type Archive struct {
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
 	Errors    []string      `json:"errors,omitempty"`
+	// Attempts — количество попыток скачивания по каждому URL.
+	Attempts map[string]int `json:"attempts,omitempty"`
+	// Job — архив собирает воркер по списку URL из POST /archive; добавлять, удалять
+	// файлы и завершать такой архив вручную нельзя.
+	Job bool `json:"job,omitempty"`
+	// Entries — по записи на каждый URL в порядке добавления.
+	Entries []FileEntry `json:"entries,omitempty"`
+}
+
+// Clone возвращает глубокую копию архива: срезы и карта не разделяются с оригиналом.
+// FileEntry не содержит ссылок, поэтому Entries достаточно скопировать поэлементно.
+func (a *Archive) Clone() *Archive {
+	if a == nil {
+		return nil
+	}
+
+	clone := *a
+	clone.Files = slices.Clone(a.Files)
+	clone.Errors = slices.Clone(a.Errors)
+	clone.Attempts = maps.Clone(a.Attempts)
+	clone.Entries = slices.Clone(a.Entries)
+	return &clone
 }
@@ -0,0 +1,38 @@
+package models
+
+// Коды ошибок в ответах API и в FileEntry.ErrorCode. Клиенты опираются на них, а не на
+// текст сообщения, поэтому коды не меняются.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeInvalidQuery         = "invalid_query"
+	CodeArchiveIDRequired    = "archive_id_required"
+	CodeArchiveNotFound      = "archive_not_found"
+	CodeFileNotFound         = "file_not_found"
+	CodeArchiveReady         = "archive_ready"
+	CodeArchiveFailed        = "archive_failed"
+	CodeArchiveJob           = "archive_job"
+	CodeArchiveFull          = "archive_full"
+	CodeArchiveEmpty         = "archive_empty"
+	CodeArchiveNotReady      = "archive_not_ready"
+	CodeTooManyFiles         = "too_many_files"
+	CodeInvalidFileURL       = "invalid_file_url"
+	CodeHostNotAllowed       = "host_not_allowed"
+	CodeUnsupportedFile      = "unsupported_file"
+	CodeFileTooLarge         = "file_too_large"
+	CodeArchiveTooLarge      = "archive_too_large"
+	CodeDownloadFailed       = "download_failed"
+	CodeDownloadTruncated    = "download_truncated"
+	CodeServerBusy           = "server_busy"
+	CodeServiceStopped       = "service_stopped"
+	CodeQueueFull            = "queue_full"
+	CodeRequestCanceled      = "request_canceled"
+	CodeArchiveBuildFailed   = "archive_build_failed"
+	CodeArchiveGetFailed     = "archive_get_failed"
+	CodeArchiveSaveFailed    = "archive_save_failed"
+	CodeArchiveDeleteFailed  = "archive_delete_failed"
+	CodeArchiveListFailed    = "archive_list_failed"
+	CodeFilesystemError      = "filesystem_error"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInternal             = "internal_error"
+)
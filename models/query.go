@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ArchiveSortField — поле, по которому сортируется список архивов.
+type ArchiveSortField string
+
+const (
+	SortByCreatedAt ArchiveSortField = "created_at"
+	SortByUpdatedAt ArchiveSortField = "updated_at"
+)
+
+// ArchiveQuery — фильтр, сортировка и страница для списка архивов.
+type ArchiveQuery struct {
+	// Statuses — допустимые статусы; пусто — любые.
+	Statuses []ArchiveStatus
+	// CreatedFrom и CreatedTo ограничивают CreatedAt полуинтервалом [CreatedFrom, CreatedTo).
+	// Нулевое значение — без границы.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// SortBy — поле сортировки, по умолчанию CreatedAt. При равных значениях порядок задает ID.
+	SortBy ArchiveSortField
+	Desc   bool
+	// Cursor — NextCursor предыдущей страницы; пусто — первая страница.
+	Cursor string
+	// Limit — размер страницы; 0 — все архивы сразу.
+	Limit int
+}
+
+// ArchivePage — страница списка архивов.
+type ArchivePage struct {
+	Archives []*Archive
+	// NextCursor — курсор следующей страницы; пусто, если это последняя.
+	NextCursor string
+}